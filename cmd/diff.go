@@ -0,0 +1,71 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adriansr/nwdevice2filebeat/model"
+	"github.com/adriansr/nwdevice2filebeat/util"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Shows the differences between two revisions of a device",
+	Run: func(cmd *cobra.Command, args []string) {
+		terminateOnError(doDiff(cmd, args))
+	},
+}
+
+func init() {
+	diffCmd.PersistentFlags().String("old", "", "Old device path")
+	diffCmd.PersistentFlags().String("new", "", "New device path")
+	diffCmd.PersistentFlags().Bool("json", false, "Output in JSON format")
+	diffCmd.PersistentFlags().Bool("lenient", false, "Skip invalid XML elements instead of failing")
+	diffCmd.MarkPersistentFlagRequired("old")
+	diffCmd.MarkPersistentFlagRequired("new")
+	rootCmd.AddCommand(diffCmd)
+}
+
+func doDiff(cmd *cobra.Command, _ []string) error {
+	oldPath, err := cmd.PersistentFlags().GetString("old")
+	if err != nil {
+		return err
+	}
+	newPath, err := cmd.PersistentFlags().GetString("new")
+	if err != nil {
+		return err
+	}
+	asJSON, err := cmd.PersistentFlags().GetBool("json")
+	if err != nil {
+		return err
+	}
+	lenient, err := cmd.PersistentFlags().GetBool("lenient")
+	if err != nil {
+		return err
+	}
+
+	var devs [2]model.Device
+	for idx, path := range []string{oldPath, newPath} {
+		warnings := util.NewWarnings(20)
+		if devs[idx], err = model.NewDeviceWithOptions(path, model.LoadOptions{Lenient: lenient}, &warnings); err != nil {
+			LogError("Failed to load device", "path", path, "reason", err)
+			return err
+		}
+		warnings.Print("loading device XML")
+	}
+
+	diff := model.Diff(&devs[0], &devs[1])
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(diff)
+	}
+	diff.WriteText(os.Stdout)
+	return nil
+}
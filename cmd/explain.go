@@ -0,0 +1,122 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adriansr/nwdevice2filebeat/config"
+	"github.com/adriansr/nwdevice2filebeat/ecs"
+	"github.com/adriansr/nwdevice2filebeat/model"
+	"github.com/adriansr/nwdevice2filebeat/parser"
+	"github.com/adriansr/nwdevice2filebeat/runtime"
+	"github.com/adriansr/nwdevice2filebeat/util"
+)
+
+var explainCmd = &cobra.Command{
+	Use:   "explain",
+	Short: "Explains how a log line is parsed by a device",
+	Run: func(cmd *cobra.Command, args []string) {
+		terminateOnError(doExplain(cmd, args))
+	},
+}
+
+func init() {
+	explainCmd.PersistentFlags().String("line", "", "Log line to explain")
+	explainCmd.PersistentFlags().String("device", "", "Input device path")
+	explainCmd.PersistentFlags().String("output", "-", "Output file where the report is written (- for stdout)")
+	explainCmd.PersistentFlags().Bool("json", false, "Output in JSON format")
+	explainCmd.PersistentFlags().String("tz", "", "Timezone")
+	explainCmd.PersistentFlags().StringSlice("local-networks", config.DefaultLocalNetworks, "Networks considered local for network direction (DIRCHK)")
+	explainCmd.PersistentFlags().StringSliceP("optimize", "O", nil, "Optimizations")
+	explainCmd.PersistentFlags().StringSliceP("fix", "F", nil, "Fixes")
+	explainCmd.PersistentFlags().Bool("lenient", false, "Skip invalid XML elements instead of failing")
+	explainCmd.PersistentFlags().String("event-categories", ecs.CategoriesFile, "Table mapping event category codes to ECS")
+	explainCmd.PersistentFlags().String("ecs-mappings", ecs.MappingsFile, "Table mapping NetWitness fields to ECS")
+	explainCmd.PersistentFlags().String("fields-merge", ecs.MergeFile, "Table defining how mapped fields are merged")
+	explainCmd.MarkPersistentFlagRequired("device")
+	explainCmd.MarkPersistentFlagRequired("line")
+	rootCmd.AddCommand(explainCmd)
+}
+
+// explainReport is the JSON output of explain.
+type explainReport struct {
+	Trace     *runtime.Trace `json:"trace"`
+	HeaderID  string         `json:"header,omitempty"`
+	MessageID string         `json:"message,omitempty"`
+	Fields    runtime.Fields `json:"fields"`
+	Errors    []string       `json:"errors"`
+}
+
+func doExplain(cmd *cobra.Command, _ []string) error {
+	cfg, err := config.NewFromCommand(cmd)
+	if err != nil {
+		LogError("Failed to parse configuration", "reason", err)
+		return err
+	}
+	line, err := cmd.PersistentFlags().GetString("line")
+	if err != nil {
+		return err
+	}
+	asJSON, err := cmd.PersistentFlags().GetBool("json")
+	if err != nil {
+		return err
+	}
+
+	warnings := util.NewWarnings(20)
+	dev, err := model.NewDeviceWithOptions(cfg.DevicePath, model.LoadOptions{Lenient: cfg.Lenient}, &warnings)
+	if err != nil {
+		LogError("Failed to load device", "path", cfg.DevicePath, "reason", err)
+		return err
+	}
+	warnings.Print("loading XML device")
+	warnings.Clear()
+
+	p, err := parser.New(dev, cfg, &warnings)
+	if err != nil {
+		LogError("Failed to parse device", "path", cfg.DevicePath, "reason", err)
+		return err
+	}
+	warnings.Print("parsing device")
+	warnings.Clear()
+
+	rt, err := runtime.New(&p, &warnings, nil)
+	if err != nil {
+		LogError("Failed to load runtime", "reason", err)
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if cfg.OutputPath != "" && cfg.OutputPath != "-" {
+		outputFile, err := os.Create(cfg.OutputPath)
+		if err != nil {
+			LogError("Failed creating output file", "path", cfg.OutputPath, "reason", err)
+			return err
+		}
+		defer outputFile.Close()
+		out = outputFile
+	}
+
+	evt, trace := rt.Explain([]byte(line))
+	if asJSON {
+		result := newRunResult(1, []byte(line), evt)
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(explainReport{
+			Trace:     trace,
+			HeaderID:  result.Header,
+			MessageID: result.Message,
+			Fields:    result.Fields,
+			Errors:    result.Errors,
+		})
+	}
+	trace.WriteText(out, evt)
+	return nil
+}
@@ -0,0 +1,74 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package cmd
+
+import (
+	"io"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adriansr/nwdevice2filebeat/model"
+	"github.com/adriansr/nwdevice2filebeat/util"
+)
+
+var fmtCmd = &cobra.Command{
+	Use:   "fmt",
+	Short: "Writes a device as normalized NetWitness XML",
+	Run: func(cmd *cobra.Command, args []string) {
+		terminateOnError(doFmt(cmd, args))
+	},
+}
+
+func init() {
+	fmtCmd.PersistentFlags().String("device", "", "Input device path")
+	fmtCmd.PersistentFlags().String("output", "", "Output file (defaults to stdout)")
+	fmtCmd.PersistentFlags().Bool("lenient", false, "Skip invalid XML elements instead of failing")
+	fmtCmd.MarkPersistentFlagRequired("device")
+	fmtCmd.MarkPersistentFlagFilename("output")
+	rootCmd.AddCommand(fmtCmd)
+}
+
+func doFmt(cmd *cobra.Command, _ []string) error {
+	devicePath, err := cmd.PersistentFlags().GetString("device")
+	if err != nil {
+		return err
+	}
+	outputPath, err := cmd.PersistentFlags().GetString("output")
+	if err != nil {
+		return err
+	}
+	lenient, err := cmd.PersistentFlags().GetBool("lenient")
+	if err != nil {
+		return err
+	}
+
+	warnings := util.NewWarnings(20)
+	dev, err := model.NewDeviceWithOptions(devicePath, model.LoadOptions{Lenient: lenient}, &warnings)
+	if err != nil {
+		LogError("Failed to load device", "path", devicePath, "reason", err)
+		return err
+	}
+	if !warnings.Print("loading device XML") {
+		log.Printf("Loaded XML %s", dev.String())
+	}
+
+	var out io.Writer = os.Stdout
+	if outputPath != "" {
+		f, err := os.Create(outputPath)
+		if err != nil {
+			LogError("Failed creating output file", "path", outputPath, "reason", err)
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	if err = dev.WriteXML(out); err != nil {
+		LogError("Failed writing XML", "reason", err)
+		return err
+	}
+	return nil
+}
@@ -8,11 +8,13 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/adriansr/nwdevice2filebeat/config"
+	"github.com/adriansr/nwdevice2filebeat/ecs"
 	"github.com/adriansr/nwdevice2filebeat/layout"
 	"github.com/adriansr/nwdevice2filebeat/model"
 	"github.com/adriansr/nwdevice2filebeat/output"
@@ -66,7 +68,10 @@ func init() {
 		cmd.PersistentFlags().StringP("format", "f", defaultPipelineFormat, "Pipeline format (js or yml)")
 		cmd.PersistentFlags().StringSliceP("optimize", "O", nil, "Optimizations")
 		cmd.PersistentFlags().StringSliceP("fix", "F", nil, "Fixes")
+		cmd.PersistentFlags().Bool("lenient", false, "Skip invalid XML elements instead of failing")
+		cmd.PersistentFlags().String("event-categories", ecs.CategoriesFile, "Table mapping event category codes to ECS")
 		cmd.MarkPersistentFlagRequired("device")
+		cmd.MarkPersistentFlagFilename("event-categories")
 		generateCmd.AddCommand(cmd)
 	}
 
@@ -122,7 +127,7 @@ func generate(cmd *cobra.Command, targetLayout string) error {
 	cfg.PipelineSettings = out.Settings()
 
 	warnings := util.NewWarnings(20)
-	dev, err := model.NewDevice(cfg.DevicePath, &warnings)
+	dev, err := model.NewDeviceWithOptions(cfg.DevicePath, model.LoadOptions{Lenient: cfg.Lenient}, &warnings)
 	if err != nil {
 		LogError("Failed to load device", "path", cfg.DevicePath, "reason", err)
 		return err
@@ -141,6 +146,13 @@ func generate(cmd *cobra.Command, targetLayout string) error {
 	warnings.Print("parsing device")
 	warnings.Clear()
 
+	if cfg.EventCategories != nil {
+		if unmapped := cfg.EventCategories.Unmapped(p.EventCategories()); len(unmapped) > 0 {
+			log.Printf("WARN: %d event categories have no ECS mapping: %s",
+				len(unmapped), strings.Join(unmapped, " "))
+		}
+	}
+
 	if err = out.Generate(p); err != nil {
 		LogError("Failed writing output", "format", cfg.PipelineFormat, "reason", err)
 		return err
@@ -169,8 +181,9 @@ func generate(cmd *cobra.Command, targetLayout string) error {
 		return nil
 	}
 
+	info := newDeviceInfo(dev)
 	if cfg.Module.Name == "" {
-		cfg.Module.Name = p.Description.Name
+		cfg.Module.Name = info.Name
 	}
 	if cfg.Module.Fileset == "" {
 		cfg.Module.Fileset = "log"
@@ -179,25 +192,28 @@ func generate(cmd *cobra.Command, targetLayout string) error {
 		cfg.Module.Port = 9010
 	}
 	if cfg.Module.Product == "" {
-		cfg.Module.Product = p.Description.DisplayName
+		cfg.Module.Product = info.DisplayName
+	}
+	if cfg.Module.Vendor == "" {
+		cfg.Module.Vendor = dev.INI.Vendor()
 	}
 	if cfg.Module.Vendor == "" {
 		cfg.Module.Vendor = cfg.Module.Name
 	}
 	if cfg.Module.Type == "" {
-		cfg.Module.Type = p.Description.Group
+		cfg.Module.Type = info.Group
 	}
 	// Icon
 	var icon string
-	path := filepath.Join("media", p.Description.Name, "logo.svg")
+	path := filepath.Join("media", info.Name, "logo.svg")
 	if _, err := os.Stat(path); err == nil {
 		icon = path
 	}
-	outLayout, err := layout.New(targetLayout, layout.Vars{
+	vars := layout.Vars{
 		LogParser: p,
 		//
 		Categories:    cfg.Module.Categories,
-		DisplayName:   p.Description.DisplayName,
+		DisplayName:   info.DisplayName,
 		Fileset:       cfg.Module.Fileset,
 		GeneratedTime: time.Now().UTC(),
 		Group:         cfg.Module.Type,
@@ -207,7 +223,13 @@ func generate(cmd *cobra.Command, targetLayout string) error {
 		Product:       cfg.Module.Product,
 		Vendor:        cfg.Module.Vendor,
 		Version:       cfg.Module.Version,
-	})
+		Tables:        p.Tables(),
+	}
+	if ini := dev.INI; ini != nil {
+		vars.DeviceType = ini.DeviceType
+		vars.DeviceID = ini.DeviceID
+	}
+	outLayout, err := layout.New(targetLayout, vars)
 	if err != nil {
 		LogError("Failed loading output layout", "format", targetLayout, "reason", err)
 		return err
@@ -227,3 +249,42 @@ func generate(cmd *cobra.Command, targetLayout string) error {
 	}
 	return nil
 }
+
+// deviceInfo is the device metadata used to fill the module defaults.
+type deviceInfo struct {
+	Name        string
+	DisplayName string
+	Group       string
+}
+
+// newDeviceInfo returns the metadata of a device. Values from the device INI
+// take precedence over the ones in the XML, as the DEVICEMESSAGES header is
+// often incomplete.
+func newDeviceInfo(dev model.Device) deviceInfo {
+	info := deviceInfo{
+		Name:        dev.Description.Name,
+		DisplayName: dev.Description.DisplayName,
+		Group:       dev.Description.Group,
+	}
+	if ini := dev.INI; ini != nil {
+		info.merge(deviceInfo{
+			Name:        ini.DatabaseName,
+			DisplayName: ini.DisplayName,
+			Group:       ini.DeviceGroup,
+		})
+	}
+	return info
+}
+
+// merge overwrites the current values with the non-empty ones in other.
+func (d *deviceInfo) merge(other deviceInfo) {
+	if other.Name != "" {
+		d.Name = other.Name
+	}
+	if other.DisplayName != "" {
+		d.DisplayName = other.DisplayName
+	}
+	if other.Group != "" {
+		d.Group = other.Group
+	}
+}
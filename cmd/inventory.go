@@ -0,0 +1,237 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/adriansr/nwdevice2filebeat/config"
+	"github.com/adriansr/nwdevice2filebeat/ecs"
+	"github.com/adriansr/nwdevice2filebeat/model"
+	"github.com/adriansr/nwdevice2filebeat/output"
+	"github.com/adriansr/nwdevice2filebeat/parser"
+	"github.com/adriansr/nwdevice2filebeat/util"
+)
+
+var inventoryCmd = &cobra.Command{
+	Use:   "inventory",
+	Short: "Reports the conversion status of all devices in a directory",
+	Run: func(cmd *cobra.Command, args []string) {
+		terminateOnError(doInventory(cmd, args))
+	},
+}
+
+func init() {
+	inventoryCmd.PersistentFlags().String("devices", "devices", "Directory containing the devices")
+	inventoryCmd.PersistentFlags().StringP("format", "f", "csv", "Report format (csv or json)")
+	inventoryCmd.PersistentFlags().String("output", "", "Output file (defaults to stdout)")
+	inventoryCmd.PersistentFlags().Bool("lenient", false, "Skip invalid XML elements instead of failing")
+	inventoryCmd.PersistentFlags().String("event-categories", ecs.CategoriesFile, "Table mapping event category codes to ECS")
+	inventoryCmd.MarkPersistentFlagDirname("devices")
+	inventoryCmd.MarkPersistentFlagFilename("output")
+	inventoryCmd.MarkPersistentFlagFilename("event-categories")
+	rootCmd.AddCommand(inventoryCmd)
+}
+
+// inventoryEntry is the conversion status of a single device.
+type inventoryEntry struct {
+	Device             string   `json:"device"`
+	Name               string   `json:"name"`
+	DisplayName        string   `json:"displayname"`
+	Group              string   `json:"group"`
+	Revision           string   `json:"revision"`
+	Headers            int      `json:"headers"`
+	Messages           int      `json:"messages"`
+	ValueMaps          int      `json:"valuemaps"`
+	Features           []string `json:"features,omitempty"`
+	LoadWarnings       int      `json:"load_warnings"`
+	ParseWarnings      int      `json:"parse_warnings"`
+	UnmappedCategories []string `json:"unmapped_categories,omitempty"`
+	JavaScriptOK       bool     `json:"javascript_ok"`
+	Error              string   `json:"error,omitempty"`
+}
+
+var inventoryCSVHeader = []string{
+	"device", "name", "displayname", "group", "revision", "headers", "messages",
+	"valuemaps", "features", "load_warnings", "parse_warnings",
+	"unmapped_categories", "javascript_ok", "error",
+}
+
+func (e inventoryEntry) csvRecord() []string {
+	return []string{
+		e.Device, e.Name, e.DisplayName, e.Group, e.Revision,
+		strconv.Itoa(e.Headers), strconv.Itoa(e.Messages), strconv.Itoa(e.ValueMaps),
+		strings.Join(e.Features, ";"),
+		strconv.Itoa(e.LoadWarnings), strconv.Itoa(e.ParseWarnings),
+		strings.Join(e.UnmappedCategories, ";"),
+		strconv.FormatBool(e.JavaScriptOK), e.Error,
+	}
+}
+
+func doInventory(cmd *cobra.Command, _ []string) error {
+	devicesDir, err := cmd.PersistentFlags().GetString("devices")
+	if err != nil {
+		return err
+	}
+	format, err := cmd.PersistentFlags().GetString("format")
+	if err != nil {
+		return err
+	}
+	if format != "csv" && format != "json" {
+		return errors.Errorf("unsupported report format: %s", format)
+	}
+	outputPath, err := cmd.PersistentFlags().GetString("output")
+	if err != nil {
+		return err
+	}
+	lenient, err := cmd.PersistentFlags().GetBool("lenient")
+	if err != nil {
+		return err
+	}
+	categoriesPath, err := cmd.PersistentFlags().GetString("event-categories")
+	if err != nil {
+		return err
+	}
+	out, err := output.Registry.Get("javascript")
+	if err != nil {
+		return err
+	}
+	categories, err := ecs.LoadCategories(categoriesPath)
+	if err != nil {
+		LogError("Failed to load event categories", "path", categoriesPath, "reason", err)
+		return err
+	}
+	cfg := config.Config{
+		Lenient:          lenient,
+		PipelineFormat:   "javascript",
+		PipelineSettings: out.Settings(),
+		EventCategories:  categories,
+	}
+
+	infos, err := ioutil.ReadDir(devicesDir)
+	if err != nil {
+		LogError("Failed to list devices", "path", devicesDir, "reason", err)
+		return err
+	}
+	var entries []inventoryEntry
+	for _, info := range infos {
+		if info.IsDir() {
+			path := filepath.Join(devicesDir, info.Name())
+			entries = append(entries, inventoryDevice(path, cfg, out))
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if outputPath != "" {
+		f, err := os.Create(outputPath)
+		if err != nil {
+			LogError("Failed creating output file", "path", outputPath, "reason", err)
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+	cw := csv.NewWriter(w)
+	cw.Write(inventoryCSVHeader)
+	for _, entry := range entries {
+		cw.Write(entry.csvRecord())
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// inventoryDevice converts the device at path using cfg as the base
+// configuration.
+func inventoryDevice(path string, cfg config.Config, out output.Output) (entry inventoryEntry) {
+	entry.Device = filepath.Base(path)
+	defer func() {
+		// Don't let a single device abort the whole report.
+		if r := recover(); r != nil {
+			entry.Error = fmt.Sprintf("panic: %v", r)
+		}
+	}()
+
+	// Only count warnings, don't keep them.
+	warnings := util.NewWarnings(0)
+	dev, err := model.NewDeviceWithOptions(path, model.LoadOptions{Lenient: cfg.Lenient}, &warnings)
+	entry.LoadWarnings = warnings.Total
+	if err != nil {
+		entry.Error = summarizeError(err)
+		return entry
+	}
+	// Same metadata as used by generate.
+	info := newDeviceInfo(dev)
+	entry.Name = info.Name
+	entry.DisplayName = info.DisplayName
+	entry.Group = info.Group
+	entry.Revision = dev.Version.Revision
+	entry.Headers = len(dev.Headers)
+	entry.Messages = len(dev.Messages)
+	entry.ValueMaps = len(dev.ValueMaps)
+	for _, feature := range []struct {
+		name  string
+		count int
+	}{
+		{"REGX", len(dev.Regexs)},
+		{"SUMDATA", len(dev.SumDatas)},
+		{"TAGVALMAP", len(dev.TagValMaps)},
+		{"VARTYPE", len(dev.VarTypes)},
+	} {
+		if feature.count > 0 {
+			entry.Features = append(entry.Features, feature.name)
+		}
+	}
+
+	cfg.DevicePath = path
+	warnings.Clear()
+	p, err := parser.New(dev, cfg, &warnings)
+	entry.ParseWarnings = warnings.Total
+	if err != nil {
+		entry.Error = summarizeError(err)
+		return entry
+	}
+	entry.UnmappedCategories = cfg.EventCategories.Unmapped(p.EventCategories())
+	err = out.Generate(p)
+	if name := out.OutputFile(); name != "" {
+		os.Remove(name)
+	}
+	if err != nil {
+		entry.Error = summarizeError(err)
+		return entry
+	}
+	entry.JavaScriptOK = true
+	return entry
+}
+
+// maxInventoryError limits the length of errors in the report, as some
+// contain hundreds of individual errors.
+const maxInventoryError = 256
+
+func summarizeError(err error) string {
+	msg := err.Error()
+	if pos := strings.IndexByte(msg, '\n'); pos >= 0 {
+		msg = msg[:pos]
+	}
+	if len(msg) > maxInventoryError {
+		msg = msg[:maxInventoryError] + "..."
+	}
+	return msg
+}
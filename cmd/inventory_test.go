@@ -0,0 +1,190 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adriansr/nwdevice2filebeat/config"
+	"github.com/adriansr/nwdevice2filebeat/ecs"
+	"github.com/adriansr/nwdevice2filebeat/model"
+	"github.com/adriansr/nwdevice2filebeat/output"
+	_ "github.com/adriansr/nwdevice2filebeat/output/javascript"
+)
+
+// writeDevice creates a device directory with the given files.
+func writeDevice(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "nwdevice_inventory")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	for name, data := range files {
+		if !assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644)) {
+			t.FailNow()
+		}
+	}
+	return dir
+}
+
+func TestInventoryDevice(t *testing.T) {
+	out, err := output.Registry.Get("javascript")
+	if !assert.NoError(t, err) {
+		return
+	}
+	cfg := config.Config{
+		PipelineFormat:   "javascript",
+		PipelineSettings: out.Settings(),
+		EventCategories: ecs.Categories{
+			"1401030000": {Code: "1401030000", Kind: "event"},
+		},
+	}
+	for _, test := range []struct {
+		title    string
+		files    map[string]string
+		lenient  bool
+		expected inventoryEntry
+		errors   string
+	}{
+		{
+			title: "metadata from INI overrides XML",
+			files: map[string]string{
+				"test.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<DEVICEMESSAGES name="acme" displayname="ACME Firewall" group="Firewall">
+<VERSION xml="1" checksum="x" revision="7" device="2.0"/>
+<HEADER id1="0001" id2="0001" content="&lt;messageid&gt;: &lt;!payload&gt;"/>
+<MESSAGE id1="login" id2="login" eventcategory="1401030000" content="user &lt;username&gt;"/>
+<MESSAGE id1="logout" id2="logout" eventcategory="1401050100" content="bye &lt;username&gt;"/>
+<VALUEMAP name="m" default="$NONE" keyvaluepairs="'a'='b'"/>
+</DEVICEMESSAGES>`,
+				"test.ini": "DatabaseName=other\nDisplayName=Other\nDeviceGroup=IDS\n",
+			},
+			expected: inventoryEntry{
+				Name:               "other",
+				DisplayName:        "Other",
+				Group:              "IDS",
+				Revision:           "7",
+				Headers:            1,
+				Messages:           2,
+				ValueMaps:          1,
+				UnmappedCategories: []string{"1401050100"},
+				JavaScriptOK:       true,
+			},
+		},
+		{
+			title: "metadata from XML completes INI",
+			files: map[string]string{
+				"test.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<DEVICEMESSAGES name="acme">
+<HEADER id1="0001" id2="0001" content="&lt;messageid&gt;: &lt;!payload&gt;"/>
+<MESSAGE id1="login" id2="login" content="user &lt;username&gt;"/>
+</DEVICEMESSAGES>`,
+				"test.ini": "DisplayName=ACME Firewall\nDeviceGroup=Firewall\n",
+			},
+			expected: inventoryEntry{
+				Name:         "acme",
+				DisplayName:  "ACME Firewall",
+				Group:        "Firewall",
+				Headers:      1,
+				Messages:     1,
+				JavaScriptOK: true,
+			},
+		},
+		{
+			title: "load error",
+			files: map[string]string{
+				"test.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<DEVICEMESSAGES name="acme">
+<RULE id="1"/>
+</DEVICEMESSAGES>`,
+			},
+			errors: "unexpected XML tag found: RULE",
+		},
+		{
+			title: "lenient",
+			files: map[string]string{
+				"test.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<DEVICEMESSAGES name="acme">
+<RULE id="1"/>
+<HEADER id1="0001" id2="0001" content="&lt;messageid&gt;: &lt;!payload&gt;"/>
+<MESSAGE id1="login" id2="login" content="user &lt;username&gt;"/>
+</DEVICEMESSAGES>`,
+			},
+			lenient: true,
+			expected: inventoryEntry{
+				Name:         "acme",
+				Headers:      1,
+				Messages:     1,
+				LoadWarnings: 1,
+				JavaScriptOK: true,
+			},
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			dir := writeDevice(t, test.files)
+			defer os.RemoveAll(dir)
+			cfg.Lenient = test.lenient
+			entry := inventoryDevice(dir, cfg, out)
+			assert.Equal(t, filepath.Base(dir), entry.Device)
+			if test.errors != "" {
+				assert.Contains(t, entry.Error, test.errors)
+				assert.False(t, entry.JavaScriptOK)
+				return
+			}
+			entry.Device = ""
+			assert.Equal(t, test.expected, entry)
+		})
+	}
+}
+
+func TestInventoryEntry_csvRecord(t *testing.T) {
+	entry := inventoryEntry{
+		Device:             "acme",
+		Name:               "acme",
+		DisplayName:        "ACME Firewall",
+		Group:              "Firewall",
+		Revision:           "7",
+		Headers:            1,
+		Messages:           2,
+		Features:           []string{"SUMDATA", "VARTYPE"},
+		UnmappedCategories: []string{"1401050100", "1901000000"},
+		JavaScriptOK:       true,
+	}
+	record := entry.csvRecord()
+	assert.Len(t, record, len(inventoryCSVHeader))
+	assert.Equal(t, []string{
+		"acme", "acme", "ACME Firewall", "Firewall", "7", "1", "2", "0",
+		"SUMDATA;VARTYPE", "0", "0", "1401050100;1901000000", "true", "",
+	}, record)
+}
+
+func Test_summarizeError(t *testing.T) {
+	assert.Equal(t, "first line", summarizeError(errors.New("first line\nsecond line")))
+	long := strings.Repeat("x", maxInventoryError+10)
+	assert.Equal(t, long[:maxInventoryError]+"...", summarizeError(errors.New(long)))
+}
+
+func Test_newDeviceInfo(t *testing.T) {
+	dev := model.Device{
+		Description: model.DeviceHeader{
+			Name:        "acme",
+			DisplayName: "ACME",
+			Group:       "Firewall",
+		},
+	}
+	assert.Equal(t, deviceInfo{Name: "acme", DisplayName: "ACME", Group: "Firewall"}, newDeviceInfo(dev))
+
+	dev.INI = &model.DeviceINI{
+		DisplayName: "ACME Firewall",
+		DeviceGroup: "IDS",
+	}
+	assert.Equal(t, deviceInfo{Name: "acme", DisplayName: "ACME Firewall", Group: "IDS"}, newDeviceInfo(dev))
+}
@@ -6,13 +6,19 @@ package cmd
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
 	"log"
 	"os"
+	"sync"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"github.com/adriansr/nwdevice2filebeat/config"
+	"github.com/adriansr/nwdevice2filebeat/ecs"
 	"github.com/adriansr/nwdevice2filebeat/model"
 	"github.com/adriansr/nwdevice2filebeat/parser"
 	"github.com/adriansr/nwdevice2filebeat/runtime"
@@ -30,10 +36,18 @@ var runCmd = &cobra.Command{
 func init() {
 	runCmd.PersistentFlags().String("logs", "l", "Input logs file path")
 	runCmd.PersistentFlags().String("device", "", "Input device path")
-	runCmd.PersistentFlags().String("output", "", "TODO")
+	runCmd.PersistentFlags().String("output", "-", "Output file where events are written as NDJSON (- for stdout)")
 	runCmd.PersistentFlags().String("tz", "", "Timezone")
+	runCmd.PersistentFlags().StringSlice("local-networks", config.DefaultLocalNetworks, "Networks considered local for network direction (DIRCHK)")
 	runCmd.PersistentFlags().StringSliceP("optimize", "O", nil, "Optimizations")
 	runCmd.PersistentFlags().StringSliceP("fix", "F", nil, "Fixes")
+	runCmd.PersistentFlags().Bool("lenient", false, "Skip invalid XML elements instead of failing")
+	runCmd.PersistentFlags().String("event-categories", ecs.CategoriesFile, "Table mapping event category codes to ECS")
+	runCmd.PersistentFlags().String("ecs-mappings", ecs.MappingsFile, "Table mapping NetWitness fields to ECS")
+	runCmd.PersistentFlags().String("fields-merge", ecs.MergeFile, "Table defining how mapped fields are merged")
+	runCmd.PersistentFlags().Bool("rsa", false, "Populate rsa.* fields in the ECS document")
+	runCmd.PersistentFlags().Bool("keep-raw", false, "Keep the NetWitness fields under rsa.raw in the ECS document")
+	runCmd.PersistentFlags().IntP("workers", "w", 1, "Number of goroutines processing lines")
 	runCmd.PersistentFlags().CountP("verbose", "v", "Verbosity level, can be repeated.")
 	runCmd.MarkPersistentFlagRequired("device")
 	runCmd.MarkPersistentFlagRequired("logs")
@@ -59,7 +73,7 @@ func doRun(cmd *cobra.Command, args []string) error {
 	defer inputFile.Close()
 
 	warnings := util.NewWarnings(20)
-	dev, err := model.NewDevice(cfg.DevicePath, &warnings)
+	dev, err := model.NewDeviceWithOptions(cfg.DevicePath, model.LoadOptions{Lenient: cfg.Lenient}, &warnings)
 	if err != nil {
 		LogError("Failed to load device", "path", cfg.DevicePath, "reason", err)
 		return err
@@ -84,27 +98,201 @@ func doRun(cmd *cobra.Command, args []string) error {
 		LogError("Failed to load runtime", "reason", err)
 		return err
 	}
-	scanner := bufio.NewScanner(inputFile)
+	var out io.Writer = os.Stdout
+	if cfg.OutputPath != "" && cfg.OutputPath != "-" {
+		outputFile, err := os.Create(cfg.OutputPath)
+		if err != nil {
+			LogError("Failed creating output file", "path", cfg.OutputPath, "reason", err)
+			return err
+		}
+		defer outputFile.Close()
+		out = outputFile
+	}
+	workers, err := cmd.PersistentFlags().GetInt("workers")
+	if err != nil {
+		LogError("Failed to parse workers", "reason", err)
+		return err
+	}
+	if workers < 1 {
+		err = errors.Errorf("need at least one worker, got %d", workers)
+		LogError("Failed to parse workers", "reason", err)
+		return err
+	}
+	writer := bufio.NewWriter(out)
+	proc := runProcessor{
+		rt:     rt,
+		mapECS: cfg.FieldMappings.IsSet(),
+	}
+	pool := runPool{
+		workers: workers,
+		process: proc.process,
+	}
 	start := time.Now()
-	var count int
-	for count = 0; scanner.Scan(); count++ {
-		line := scanner.Bytes()
-		fields, errs := rt.Process(line)
-		log.Printf("Processed line #%d <<%s>>", count, line)
-		log.Printf("Got %d fields:", len(fields))
-		for k, v := range fields {
-			log.Printf("  '%s': '%s'", k, v)
+	count, stats, readErr, writeErr := pool.run(inputFile, writer)
+	if writeErr == nil {
+		writeErr = writer.Flush()
+	}
+	if writeErr != nil {
+		LogError("Failed writing output", "path", cfg.OutputPath, "reason", writeErr)
+		return writeErr
+	}
+	if readErr != nil {
+		LogError("Failed reading logs file", "path", logPath, "reason", readErr)
+		return readErr
+	}
+	took := time.Now().Sub(start)
+	log.Printf("Processed %d lines in %v (%.0f eps)",
+		count, took, eps(count, took))
+	if workers > 1 {
+		for idx, st := range stats {
+			log.Printf("  worker #%d: %d lines in %v (%.0f eps)",
+				idx, st.lines, st.busy, eps(st.lines, st.busy))
 		}
-		if len(errs) > 0 {
-			log.Printf("Got %d errors:", len(errs))
-			for idx, err := range errs {
-				log.Printf("  err[%d] = %v", idx, err)
+	}
+	return nil
+}
+
+func eps(lines int, took time.Duration) float64 {
+	if took <= 0 {
+		return 0
+	}
+	return float64(lines) / took.Seconds()
+}
+
+// runPool processes lines in parallel. Lines are distributed to the workers
+// as they're read, and their results are written in input order.
+type runPool struct {
+	workers int
+	// process converts a line to its output. It's called concurrently.
+	process func(runJob) runOutput
+}
+
+// workerStats counts the lines processed by a worker and the time it spent
+// processing them.
+type workerStats struct {
+	lines int
+	busy  time.Duration
+}
+
+type runJob struct {
+	seq  int
+	line []byte
+}
+
+type runOutput struct {
+	seq  int
+	data []byte
+	err  error
+}
+
+// Maximum number of lines per worker that are read but not yet written.
+const linesInFlightPerWorker = 64
+
+func (p runPool) run(input io.Reader, out io.Writer) (count int, stats []workerStats, readErr, writeErr error) {
+	jobs := make(chan runJob, p.workers)
+	results := make(chan runOutput, p.workers)
+	// Limits how far the reader can get ahead of the writer when a line is
+	// slow to process.
+	inFlight := make(chan struct{}, p.workers*linesInFlightPerWorker)
+
+	go func() {
+		defer close(jobs)
+		scanner := bufio.NewScanner(input)
+		for seq := 0; scanner.Scan(); seq++ {
+			inFlight <- struct{}{}
+			// The scanner reuses its buffer.
+			line := append([]byte(nil), scanner.Bytes()...)
+			jobs <- runJob{seq: seq, line: line}
+		}
+		readErr = scanner.Err()
+	}()
+
+	stats = make([]workerStats, p.workers)
+	var wg sync.WaitGroup
+	wg.Add(p.workers)
+	for idx := range stats {
+		go func(st *workerStats) {
+			defer wg.Done()
+			for job := range jobs {
+				start := time.Now()
+				res := p.process(job)
+				st.busy += time.Since(start)
+				st.lines++
+				results <- res
 			}
+		}(&stats[idx])
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
 
+	pending := make(map[int]runOutput)
+	for res := range results {
+		pending[res.seq] = res
+		for {
+			next, found := pending[count]
+			if !found {
+				break
+			}
+			delete(pending, count)
+			if writeErr == nil {
+				if writeErr = next.err; writeErr == nil {
+					_, writeErr = out.Write(next.data)
+				}
+			}
+			count++
+			<-inFlight
 		}
 	}
-	took := time.Now().Sub(start)
-	log.Printf("Processed %d lines in %v (%.0f eps)",
-		count, took, float64(count)/took.Seconds())
-	return nil
+	return count, stats, readErr, writeErr
+}
+
+// runProcessor converts lines to runResult documents.
+type runProcessor struct {
+	rt     *runtime.Processor
+	mapECS bool
+}
+
+func (p runProcessor) process(job runJob) runOutput {
+	evt := p.rt.ProcessEvent(job.line)
+	result := newRunResult(job.seq+1, job.line, evt)
+	if evt.Fields != nil && p.mapECS {
+		result.ECS = p.rt.Map(evt.Fields)
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(result)
+	return runOutput{seq: job.seq, data: buf.Bytes(), err: err}
+}
+
+// runResult is the document written for every processed line.
+type runResult struct {
+	// Line is the line number in the logs file, starting at 1.
+	Line     int              `json:"line"`
+	Original string           `json:"original"`
+	Header   string           `json:"header,omitempty"`
+	Message  string           `json:"message,omitempty"`
+	Fields   runtime.Fields   `json:"fields"`
+	Errors   []string         `json:"errors"`
+	ECS      runtime.Document `json:"ecs,omitempty"`
+}
+
+func newRunResult(lineNum int, line []byte, evt runtime.Event) runResult {
+	result := runResult{
+		Line:     lineNum,
+		Original: string(line),
+		Header:   evt.HeaderID,
+		Message:  evt.MessageID,
+		Fields:   evt.Fields,
+		Errors:   make([]string, len(evt.Errors)),
+	}
+	if result.Fields == nil {
+		result.Fields = runtime.Fields{}
+	}
+	for idx, err := range evt.Errors {
+		result.Errors[idx] = err.Error()
+	}
+	return result
 }
@@ -0,0 +1,164 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// numberedLines returns n lines containing their sequence number.
+func numberedLines(n int) string {
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, "%d\n", i)
+	}
+	return sb.String()
+}
+
+// echoJob outputs the line that was read.
+func echoJob(job runJob) runOutput {
+	return runOutput{seq: job.seq, data: append(job.line, '\n')}
+}
+
+// failingWriter fails after a number of writes.
+type failingWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.writes == 0 {
+		return 0, errors.New("disk full")
+	}
+	w.writes--
+	return w.Buffer.Write(p)
+}
+
+// failingReader returns an error after its data has been read.
+type failingReader struct {
+	data *strings.Reader
+}
+
+func (r failingReader) Read(p []byte) (int, error) {
+	if r.data.Len() == 0 {
+		return 0, errors.New("connection reset")
+	}
+	return r.data.Read(p)
+}
+
+func TestRunPool_run(t *testing.T) {
+	const numLines = 500
+	for _, workers := range []int{1, 2, 8} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			pool := runPool{
+				workers: workers,
+				process: func(job runJob) runOutput {
+					// Slow lines cause results to arrive out of order.
+					if job.seq%17 == 0 {
+						time.Sleep(time.Millisecond)
+					}
+					return echoJob(job)
+				},
+			}
+			var out bytes.Buffer
+			count, stats, readErr, writeErr := pool.run(strings.NewReader(numberedLines(numLines)), &out)
+			assert.NoError(t, readErr)
+			assert.NoError(t, writeErr)
+			assert.Equal(t, numLines, count)
+			assert.Equal(t, numberedLines(numLines), out.String())
+			if assert.Len(t, stats, workers) {
+				var total int
+				for _, st := range stats {
+					total += st.lines
+				}
+				assert.Equal(t, numLines, total)
+			}
+		})
+	}
+}
+
+func TestRunPool_runBackPressure(t *testing.T) {
+	const workers = 2
+	const numLines = 10 * workers * linesInFlightPerWorker
+	release := make(chan struct{})
+	var processed int64
+	pool := runPool{
+		workers: workers,
+		process: func(job runJob) runOutput {
+			// The first line blocks its output, so nothing can be written.
+			if job.seq == 0 {
+				<-release
+			}
+			atomic.AddInt64(&processed, 1)
+			return echoJob(job)
+		},
+	}
+	done := make(chan struct{})
+	var out bytes.Buffer
+	var count int
+	go func() {
+		defer close(done)
+		count, _, _, _ = pool.run(strings.NewReader(numberedLines(numLines)), &out)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	assert.True(t, atomic.LoadInt64(&processed) < workers*linesInFlightPerWorker,
+		"reader didn't stop after %d lines in flight", workers*linesInFlightPerWorker)
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for pool")
+	}
+	assert.Equal(t, numLines, count)
+	assert.Equal(t, numberedLines(numLines), out.String())
+}
+
+func TestRunPool_runErrors(t *testing.T) {
+	const numLines = 100
+	t.Run("write error", func(t *testing.T) {
+		pool := runPool{workers: 4, process: echoJob}
+		out := &failingWriter{writes: 10}
+		count, _, readErr, writeErr := pool.run(strings.NewReader(numberedLines(numLines)), out)
+		assert.NoError(t, readErr)
+		assert.EqualError(t, writeErr, "disk full")
+		// All the input is consumed, but nothing is written after the error.
+		assert.Equal(t, numLines, count)
+		assert.Equal(t, numberedLines(10), out.String())
+	})
+	t.Run("encoding error", func(t *testing.T) {
+		pool := runPool{
+			workers: 4,
+			process: func(job runJob) runOutput {
+				if job.seq == 20 {
+					return runOutput{seq: job.seq, err: errors.New("bad line")}
+				}
+				return echoJob(job)
+			},
+		}
+		var out bytes.Buffer
+		count, _, _, writeErr := pool.run(strings.NewReader(numberedLines(numLines)), &out)
+		assert.EqualError(t, writeErr, "bad line")
+		assert.Equal(t, numLines, count)
+		assert.Equal(t, numberedLines(20), out.String())
+	})
+	t.Run("read error", func(t *testing.T) {
+		pool := runPool{workers: 4, process: echoJob}
+		var out bytes.Buffer
+		input := failingReader{data: strings.NewReader(numberedLines(numLines))}
+		count, _, readErr, writeErr := pool.run(input, &out)
+		assert.EqualError(t, readErr, "connection reset")
+		assert.NoError(t, writeErr)
+		assert.Equal(t, numLines, count)
+		assert.Equal(t, numberedLines(numLines), out.String())
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"net"
 	"time"
 
+	"github.com/adriansr/nwdevice2filebeat/ecs"
 	"github.com/adriansr/nwdevice2filebeat/util"
 )
 
@@ -33,6 +34,15 @@ type Config struct {
 		Vendor     string   // Vendor name (observer.vendor)
 		Version    string   // Module version
 	}
+	// Lenient enables recovery from errors when loading the device XML.
+	Lenient bool
+
+	// EventCategories maps MESSAGE eventcategory codes to ECS categorization.
+	EventCategories ecs.Categories
+
+	// FieldMappings maps NetWitness fields to ECS and rsa.* fields.
+	FieldMappings ecs.Mappings
+
 	// Verbosity is the logging verbosity level for this invocation of the tool.
 	Verbosity util.VerbosityLevel
 
@@ -79,6 +89,25 @@ type Fixes struct {
 	// TrimEdgeSpace strips space at the start and end of MESSAGES, as it seems
 	// to be a common error to add this extra space.
 	TrimEdgeSpace bool
+
+	// CatchAll adds a generic MESSAGE for messageids that have no mapping, so
+	// that unknown events are still ingested with their header fields.
+	CatchAll bool
+
+	// ReorderShadowed moves MESSAGEs that share an id2 so that more specific
+	// patterns are tried before the more general ones that shadow them.
+	ReorderShadowed bool
+}
+
+// DefaultLocalNetworks are the networks considered local for network direction
+// calculation when none are configured.
+var DefaultLocalNetworks = []string{
+	"10.0.0.0/8",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"127.0.0.0/8",
+	"fc00::/7",
+	"::1/128",
 }
 
 type Runtime struct {
@@ -87,6 +116,14 @@ type Runtime struct {
 
 	// For network direction calculation (DIRCHK function).
 	LocalNetworks []net.IPNet
+
+	// MapRSA populates rsa.* fields in the ECS document (rsa option in the
+	// javascript pipeline).
+	MapRSA bool
+
+	// KeepRaw stores the NetWitness fields under rsa.raw in the ECS document
+	// (keep_raw option in the javascript pipeline).
+	KeepRaw bool
 }
 
 // PipelineSettings contains the configuration that a given pipeline format
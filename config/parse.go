@@ -5,11 +5,15 @@
 package config
 
 import (
+	"net"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
+	"github.com/adriansr/nwdevice2filebeat/ecs"
 	"github.com/adriansr/nwdevice2filebeat/util"
 )
 
@@ -32,6 +36,9 @@ func NewFromCommand(cmd *cobra.Command) (cfg Config, err error) {
 	cfg.Module.Vendor, _ = cmd.PersistentFlags().GetString("vendor")
 	cfg.Module.Product, _ = cmd.PersistentFlags().GetString("product")
 	cfg.Module.Type, _ = cmd.PersistentFlags().GetString("type")
+	cfg.Lenient, _ = cmd.PersistentFlags().GetBool("lenient")
+	cfg.Runtime.MapRSA, _ = cmd.PersistentFlags().GetBool("rsa")
+	cfg.Runtime.KeepRaw, _ = cmd.PersistentFlags().GetBool("keep-raw")
 
 	if opts, err := cmd.PersistentFlags().GetStringSlice("optimize"); err == nil {
 		if cfg.Opt, err = parseOpts(opts); err != nil {
@@ -48,6 +55,28 @@ func NewFromCommand(cmd *cobra.Command) (cfg Config, err error) {
 			return cfg, errors.Wrapf(err, "unable to parse timezone: '%s'", tzName)
 		}
 	}
+	if path, err := cmd.PersistentFlags().GetString("event-categories"); err == nil {
+		// A missing table is only an error when explicitly requested.
+		cfg.EventCategories, err = ecs.LoadCategories(path)
+		if err != nil && (cmd.PersistentFlags().Changed("event-categories") || !os.IsNotExist(err)) {
+			return cfg, err
+		}
+	}
+	if mappingsPath, err := cmd.PersistentFlags().GetString("ecs-mappings"); err == nil {
+		mergePath, _ := cmd.PersistentFlags().GetString("fields-merge")
+		// As with event categories, missing tables are only an error when
+		// explicitly requested.
+		cfg.FieldMappings, err = ecs.LoadMappings(mappingsPath, mergePath)
+		if err != nil && (cmd.PersistentFlags().Changed("ecs-mappings") ||
+			cmd.PersistentFlags().Changed("fields-merge") || !os.IsNotExist(err)) {
+			return cfg, err
+		}
+	}
+	if networks, err := cmd.PersistentFlags().GetStringSlice("local-networks"); err == nil {
+		if cfg.Runtime.LocalNetworks, err = ParseNetworks(networks); err != nil {
+			return cfg, err
+		}
+	}
 	if verbosity, err := cmd.PersistentFlags().GetCount("verbose"); err == nil {
 		cfg.Verbosity = util.VerbosityLevel(verbosity)
 	}
@@ -78,6 +107,10 @@ func parseFix(flags []string) (fix Fixes, err error) {
 		switch flag {
 		case "space", "whitespace", "w", "s":
 			fix.TrimEdgeSpace = true
+		case "catchall", "c":
+			fix.CatchAll = true
+		case "reorder", "r":
+			fix.ReorderShadowed = true
 		default:
 			return fix, errors.Errorf("unknown fix flag: %s", flag)
 		}
@@ -85,6 +118,19 @@ func parseFix(flags []string) (fix Fixes, err error) {
 	return fix, nil
 }
 
+// ParseNetworks parses a list of networks in CIDR notation.
+func ParseNetworks(networks []string) (result []net.IPNet, err error) {
+	result = make([]net.IPNet, 0, len(networks))
+	for _, str := range networks {
+		_, network, err := net.ParseCIDR(strings.TrimSpace(str))
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse local network: '%s'", str)
+		}
+		result = append(result, *network)
+	}
+	return result, nil
+}
+
 var timezoneFormats = []string{"-07", "-0700", "-07:00"}
 
 // Copied from beats/libbeat/processor/timestamp.go
@@ -0,0 +1,195 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package ecs
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CategoriesFile is the default path to the table that maps NetWitness event
+// category codes to ECS categorization fields.
+const CategoriesFile = "event-categories.csv"
+
+const (
+	colCode = iota
+	colKind
+	colCategory
+	colType
+	colOutcome
+	colDescription
+	numColumns
+)
+
+// Separator for columns that accept multiple values (category and type).
+const valueSeparator = "|"
+
+// Allowed values for the ECS categorization fields (ECS 1.8).
+var (
+	allowedKinds = makeSet("alert", "event", "metric", "state", "pipeline_error", "signal")
+
+	allowedCategories = makeSet("authentication", "configuration", "database",
+		"driver", "file", "host", "iam", "intrusion_detection", "malware",
+		"network", "package", "process", "registry", "session", "web")
+
+	allowedTypes = makeSet("access", "admin", "allowed", "change", "connection",
+		"creation", "deletion", "denied", "end", "error", "group", "info",
+		"installation", "protocol", "start", "user")
+
+	allowedOutcomes = makeSet("failure", "success", "unknown")
+)
+
+// Category is the ECS categorization for a NetWitness event category code.
+type Category struct {
+	Code        string
+	Kind        string
+	Category    []string
+	Type        []string
+	Outcome     string
+	Description string
+}
+
+// Categories maps NetWitness event category codes (eventcategory attribute
+// in MESSAGE) to their ECS categorization.
+type Categories map[string]Category
+
+// LoadCategories loads the event categories table from a CSV file.
+func LoadCategories(path string) (Categories, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	c, err := readCategories(f)
+	return c, errors.Wrapf(err, "loading %s", path)
+}
+
+func readCategories(r io.Reader) (Categories, error) {
+	csvReader := csv.NewReader(r)
+	csvReader.FieldsPerRecord = numColumns
+	csvReader.TrimLeadingSpace = true
+	table := make(Categories)
+	for lineNum := 1; ; lineNum++ {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed reading line %d", lineNum)
+		}
+		if lineNum == 1 && record[colCode] == "code" {
+			continue
+		}
+		cat := Category{
+			Code:        record[colCode],
+			Kind:        record[colKind],
+			Category:    splitValues(record[colCategory]),
+			Type:        splitValues(record[colType]),
+			Outcome:     record[colOutcome],
+			Description: record[colDescription],
+		}
+		if err = cat.validate(); err != nil {
+			return nil, errors.Wrapf(err, "invalid entry at line %d", lineNum)
+		}
+		if _, found := table[cat.Code]; found {
+			return nil, errors.Errorf("duplicate code %s at line %d", cat.Code, lineNum)
+		}
+		table[cat.Code] = cat
+	}
+	return table, nil
+}
+
+func (c Category) validate() error {
+	if !isCode(c.Code) {
+		return errors.Errorf("code must be 10 digits: '%s'", c.Code)
+	}
+	if c.Kind != "" && !allowedKinds[c.Kind] {
+		return errors.Errorf("unknown kind '%s'", c.Kind)
+	}
+	for _, value := range c.Category {
+		if !allowedCategories[value] {
+			return errors.Errorf("unknown category '%s'", value)
+		}
+	}
+	for _, value := range c.Type {
+		if !allowedTypes[value] {
+			return errors.Errorf("unknown type '%s'", value)
+		}
+	}
+	if c.Outcome != "" && !allowedOutcomes[c.Outcome] {
+		return errors.Errorf("unknown outcome '%s'", c.Outcome)
+	}
+	return nil
+}
+
+// Lookup returns the categorization for the given code. Codes are
+// hierarchical, with every pair of digits adding a level of detail, so when
+// the code itself is not in the table the closest parent is used, i.e.
+// 1401030000 falls back to 1401000000, then 1400000000.
+func (c Categories) Lookup(code string) (Category, bool) {
+	code = strings.TrimSpace(code)
+	if !isCode(code) {
+		return Category{}, false
+	}
+	key := []byte(code)
+	for pos := len(key); pos >= 2; pos -= 2 {
+		if pos < len(key) {
+			if key[pos] == '0' && key[pos+1] == '0' {
+				continue
+			}
+			key[pos], key[pos+1] = '0', '0'
+		}
+		if cat, found := c[string(key)]; found {
+			return cat, true
+		}
+	}
+	return Category{}, false
+}
+
+// Unmapped returns the codes in the given list that have no categorization,
+// sorted.
+func (c Categories) Unmapped(codes []string) (unmapped []string) {
+	for _, code := range codes {
+		if _, found := c.Lookup(code); !found {
+			unmapped = append(unmapped, code)
+		}
+	}
+	sort.Strings(unmapped)
+	return unmapped
+}
+
+func isCode(s string) bool {
+	if len(s) != 10 {
+		return false
+	}
+	for _, chr := range s {
+		if chr < '0' || chr > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func splitValues(s string) (values []string) {
+	for _, value := range strings.Split(s, valueSeparator) {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func makeSet(values ...string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
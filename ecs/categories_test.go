@@ -0,0 +1,124 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package ecs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadCategories(t *testing.T) {
+	for _, test := range []struct {
+		title    string
+		csv      string
+		expected Categories
+		err      string
+	}{
+		{
+			title: "valid",
+			csv: `code,kind,category,type,outcome,description
+1401030000,event,authentication,start,failure,Failed logon
+1402020200,event,iam,user|creation,,User created
+1901000000,event,,,,Other
+`,
+			expected: Categories{
+				"1401030000": {
+					Code:        "1401030000",
+					Kind:        "event",
+					Category:    []string{"authentication"},
+					Type:        []string{"start"},
+					Outcome:     "failure",
+					Description: "Failed logon",
+				},
+				"1402020200": {
+					Code:        "1402020200",
+					Kind:        "event",
+					Category:    []string{"iam"},
+					Type:        []string{"user", "creation"},
+					Description: "User created",
+				},
+				"1901000000": {
+					Code:        "1901000000",
+					Kind:        "event",
+					Description: "Other",
+				},
+			},
+		},
+		{
+			title: "bad code",
+			csv:   "14010300,event,,,,x\n",
+			err:   "code must be 10 digits",
+		},
+		{
+			title: "bad category",
+			csv:   "1401030000,event,login,,,x\n",
+			err:   "unknown category 'login'",
+		},
+		{
+			title: "duplicate",
+			csv:   "1401030000,event,,,,x\n1401030000,alert,,,,y\n",
+			err:   "duplicate code 1401030000 at line 2",
+		},
+		{
+			title: "missing columns",
+			csv:   "1401030000,event\n",
+			err:   "wrong number of fields",
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			table, err := readCategories(strings.NewReader(test.csv))
+			if test.err != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), test.err)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, table)
+		})
+	}
+}
+
+func TestCategories_Lookup(t *testing.T) {
+	table := Categories{
+		"1400000000": {Code: "1400000000"},
+		"1401000000": {Code: "1401000000"},
+		"1401030000": {Code: "1401030000"},
+		"1402010300": {Code: "1402010300"},
+	}
+	for _, test := range []struct {
+		code     string
+		expected string
+	}{
+		{code: "1401030000", expected: "1401030000"},
+		{code: "1401030100", expected: "1401030000"},
+		{code: "1401060000", expected: "1401000000"},
+		{code: "1402010301", expected: "1402010300"},
+		{code: "1402020000", expected: "1400000000"},
+		{code: " 1401030000 ", expected: "1401030000"},
+		{code: "1501040000"},
+		{code: "14"},
+		{code: "abcdefghij"},
+	} {
+		t.Run(test.code, func(t *testing.T) {
+			cat, found := table.Lookup(test.code)
+			assert.Equal(t, test.expected != "", found)
+			assert.Equal(t, test.expected, cat.Code)
+		})
+	}
+	assert.Equal(t,
+		[]string{"1501040000", "1601000000"},
+		table.Unmapped([]string{"1601000000", "1401070000", "1501040000"}))
+}
+
+func TestLoadCategories(t *testing.T) {
+	table, err := LoadCategories("../" + CategoriesFile)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NotEmpty(t, table)
+}
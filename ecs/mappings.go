@@ -0,0 +1,316 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package ecs
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// MappingsFile is the default path to the table that maps NetWitness
+	// fields to ECS and rsa.* fields.
+	MappingsFile = "ecs-mappings.csv"
+
+	// MergeFile is the default path to the table that defines how fields
+	// mapped from more than one NetWitness field are merged.
+	MergeFile = "fields-merge.csv"
+)
+
+// Columns in the mappings table (same as scripts/shared.py).
+const (
+	colMappingSource = 4
+	colMappingType   = 6
+	colMappingMap    = 11
+	colMappingAlt    = 12
+	colMappingExtra  = 17
+	minMappingCols   = 17
+)
+
+// Conversion is the type a NetWitness field is converted to before mapping.
+type Conversion string
+
+const (
+	ConvertNone   Conversion = ""
+	ConvertDate   Conversion = "date"
+	ConvertIP     Conversion = "ip"
+	ConvertLong   Conversion = "long"
+	ConvertDouble Conversion = "double"
+	ConvertMAC    Conversion = "mac"
+)
+
+var typeConversions = map[string]Conversion{
+	"":        ConvertNone,
+	"Text":    ConvertNone,
+	"TimeT":   ConvertDate,
+	"IPv4":    ConvertIP,
+	"IPv6":    ConvertIP,
+	"UInt64":  ConvertLong,
+	"UInt32":  ConvertLong,
+	"UInt16":  ConvertLong,
+	"UInt8":   ConvertLong,
+	"Int64":   ConvertLong,
+	"Int32":   ConvertLong,
+	"Int16":   ConvertLong,
+	"Float64": ConvertDouble,
+	"Float32": ConvertDouble,
+	"MAC":     ConvertMAC,
+}
+
+// Mode is how a value is stored into its target field. These are the setters
+// in the javascript library (fld_set, fld_append, fld_prio, ...).
+type Mode string
+
+const (
+	// ModeSet overwrites the target field.
+	ModeSet Mode = "set"
+	// ModeAppend adds the value to a list without duplicates.
+	ModeAppend Mode = "append"
+	// ModePrio keeps the value from the source with the lowest priority.
+	ModePrio Mode = "prio"
+	// ModeECSOutcome normalizes the value to a valid event.outcome.
+	ModeECSOutcome Mode = "ecs_outcome"
+)
+
+// Target is a field where a NetWitness field is mapped to.
+type Target struct {
+	Field string
+	Mode  Mode
+	// Prio is only used by ModePrio. Lower values take precedence.
+	Prio int
+}
+
+// Mapping defines how a NetWitness field is converted and stored.
+type Mapping struct {
+	Convert Conversion
+	To      []Target
+}
+
+// FieldMappings maps NetWitness field names to their mapping.
+type FieldMappings map[string]Mapping
+
+// Mappings contains the tables used to populate an event from the NetWitness
+// fields. These are the ecs_mappings and rsa_mappings tables in the javascript
+// library, generated by scripts/gen-field-mappings.py from the same files.
+type Mappings struct {
+	ECS FieldMappings
+	RSA FieldMappings
+	// Types is the conversion for the format of every NetWitness field that
+	// is not text and is mapped to an ECS or rsa.* field.
+	Types map[string]Conversion
+}
+
+// IsSet returns if the mappings have been loaded.
+func (m Mappings) IsSet() bool {
+	return len(m.ECS) > 0 || len(m.RSA) > 0
+}
+
+// IsRSAField returns if a target field is in the rsa.* namespace.
+func IsRSAField(path string) bool {
+	return strings.HasPrefix(path, "rsa.")
+}
+
+type override struct {
+	mode       Mode
+	priorities map[string]int
+}
+
+// LoadMappings loads the field mappings table and the merge table from CSV
+// files.
+func LoadMappings(mappingsPath, mergePath string) (m Mappings, err error) {
+	merge, err := os.Open(mergePath)
+	if err != nil {
+		return m, err
+	}
+	defer merge.Close()
+	overrides, err := readOverrides(merge)
+	if err != nil {
+		return m, errors.Wrapf(err, "loading %s", mergePath)
+	}
+	f, err := os.Open(mappingsPath)
+	if err != nil {
+		return m, err
+	}
+	defer f.Close()
+	m, err = readMappings(f, overrides)
+	return m, errors.Wrapf(err, "loading %s", mappingsPath)
+}
+
+func readOverrides(r io.Reader) (map[string]override, error) {
+	csvReader := csv.NewReader(r)
+	csvReader.FieldsPerRecord = -1
+	csvReader.Comment = '#'
+	overrides := make(map[string]override)
+	for lineNum := 1; ; lineNum++ {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed reading line %d", lineNum)
+		}
+		if len(record) < 2 {
+			return nil, errors.Errorf("missing mode at line %d", lineNum)
+		}
+		field := record[0]
+		if _, found := overrides[field]; found {
+			return nil, errors.Errorf("repeated override entry '%s' at line %d", field, lineNum)
+		}
+		var o override
+		switch record[1] {
+		case "append":
+			if len(record) > 2 {
+				return nil, errors.Errorf("excess data after append override at line %d", lineNum)
+			}
+			o.mode = ModeAppend
+		case "by_prio":
+			if len(record) < 4 {
+				return nil, errors.Errorf("need at least 2 fields for by_prio override at line %d", lineNum)
+			}
+			o.mode = ModePrio
+			o.priorities = make(map[string]int, len(record)-2)
+			for prio, src := range record[2:] {
+				o.priorities[src] = prio
+			}
+		case "map":
+			if len(record) != 3 {
+				return nil, errors.Errorf("need one param for map override at line %d", lineNum)
+			}
+			o.mode = Mode(record[2])
+			if o.mode != ModeECSOutcome {
+				return nil, errors.Errorf("unknown map mode '%s' at line %d", record[2], lineNum)
+			}
+		default:
+			return nil, errors.Errorf("unknown override mode '%s' at line %d", record[1], lineNum)
+		}
+		overrides[field] = o
+	}
+	return overrides, nil
+}
+
+func readMappings(r io.Reader, overrides map[string]override) (m Mappings, err error) {
+	csvReader := csv.NewReader(r)
+	csvReader.FieldsPerRecord = -1
+	bySource := make(map[string]Mapping)
+	byTarget := make(map[string][]string)
+	types := make(map[string]Conversion)
+	for lineNum := 1; ; lineNum++ {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return m, errors.Wrapf(err, "failed reading line %d", lineNum)
+		}
+		if lineNum == 1 && record[0] == "revision" {
+			continue
+		}
+		if len(record) < minMappingCols {
+			return m, errors.Errorf("wrong number of fields at line %d", lineNum)
+		}
+		src := record[colMappingSource]
+		conv, found := typeConversions[record[colMappingType]]
+		if !found {
+			return m, errors.Errorf("unsupported type '%s' at line %d", record[colMappingType], lineNum)
+		}
+		if _, found := bySource[src]; found {
+			return m, errors.Errorf("repeated field '%s' at line %d", src, lineNum)
+		}
+		mapping := Mapping{Convert: conv}
+		hasECS := false
+		for _, col := range []int{colMappingMap, colMappingAlt, colMappingExtra} {
+			if col >= len(record) || record[col] == "" {
+				continue
+			}
+			tgt := Target{Field: record[col], Mode: ModeSet}
+			if o, found := overrides[tgt.Field]; found {
+				tgt.Mode = o.mode
+				if tgt.Mode == ModePrio {
+					if tgt.Prio, found = o.priorities[src]; !found {
+						return m, errors.Errorf("no priority for '%s' in '%s' at line %d", src, tgt.Field, lineNum)
+					}
+				}
+			}
+			hasECS = hasECS || !IsRSAField(tgt.Field)
+			mapping.To = append(mapping.To, tgt)
+		}
+		// IP fields also populate related.ip if they map to any ECS field.
+		if conv == ConvertIP && hasECS {
+			mapping.To = append(mapping.To, Target{Field: "related.ip", Mode: ModeAppend})
+		}
+		if conv != ConvertNone && len(mapping.To) > 0 {
+			types[src] = conv
+		}
+		bySource[src] = mapping
+		for _, tgt := range mapping.To {
+			byTarget[tgt.Field] = append(byTarget[tgt.Field], src)
+		}
+	}
+	if err = validateTargets(bySource, byTarget); err != nil {
+		return m, err
+	}
+	m.ECS = make(FieldMappings)
+	m.RSA = make(FieldMappings)
+	m.Types = types
+	for src, mapping := range bySource {
+		ecs, rsa := mapping.split()
+		if len(ecs.To) > 0 {
+			m.ECS[src] = ecs
+		}
+		if len(rsa.To) > 0 {
+			m.RSA[src] = rsa
+		}
+	}
+	return m, nil
+}
+
+// validateTargets checks that every target field is set in a single mode and
+// from fields of the same type. Unlike scripts/gen-field-mappings.py, fields
+// in set mode can have more than one source (i.e. saddr and saddr_v6), as in
+// the tables shipped with the javascript library.
+func validateTargets(bySource map[string]Mapping, byTarget map[string][]string) error {
+	targets := make([]string, 0, len(byTarget))
+	for field := range byTarget {
+		targets = append(targets, field)
+	}
+	sort.Strings(targets)
+	for _, field := range targets {
+		var mode Mode
+		var conv Conversion
+		for idx, src := range byTarget[field] {
+			mapping := bySource[src]
+			for _, tgt := range mapping.To {
+				if tgt.Field != field {
+					continue
+				}
+				if idx == 0 {
+					mode, conv = tgt.Mode, mapping.Convert
+				} else if tgt.Mode != mode {
+					return errors.Errorf("field %s is set in different modes: %s and %s", field, mode, tgt.Mode)
+				} else if mapping.Convert != conv {
+					return errors.Errorf("field %s is set from different types: '%s' and '%s'", field, conv, mapping.Convert)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (m Mapping) split() (ecs, rsa Mapping) {
+	ecs.Convert, rsa.Convert = m.Convert, m.Convert
+	for _, tgt := range m.To {
+		if IsRSAField(tgt.Field) {
+			rsa.To = append(rsa.To, tgt)
+		} else {
+			ecs.To = append(ecs.To, tgt)
+		}
+	}
+	return ecs, rsa
+}
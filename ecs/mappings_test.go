@@ -0,0 +1,171 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package ecs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mappingRow builds a line of the mappings table.
+func mappingRow(src, typ, ecs, alt, extra string) string {
+	cols := make([]string, minMappingCols+1)
+	cols[colMappingSource] = src
+	cols[colMappingType] = typ
+	cols[colMappingMap] = ecs
+	cols[colMappingAlt] = alt
+	cols[colMappingExtra] = extra
+	return strings.Join(cols, ",") + "\n"
+}
+
+func TestReadOverrides(t *testing.T) {
+	for _, test := range []struct {
+		title    string
+		csv      string
+		expected map[string]override
+		err      string
+	}{
+		{
+			title: "valid",
+			csv: `# Target field, mode, extra..
+related.user,append
+host.name,by_prio,hostname,host
+event.outcome,map,ecs_outcome
+`,
+			expected: map[string]override{
+				"related.user":  {mode: ModeAppend},
+				"host.name":     {mode: ModePrio, priorities: map[string]int{"hostname": 0, "host": 1}},
+				"event.outcome": {mode: ModeECSOutcome},
+			},
+		},
+		{
+			title: "repeated",
+			csv:   "related.user,append\nrelated.user,append\n",
+			err:   "repeated override entry 'related.user' at line 2",
+		},
+		{
+			title: "prio without fields",
+			csv:   "host.name,by_prio,hostname\n",
+			err:   "need at least 2 fields",
+		},
+		{
+			title: "unknown map",
+			csv:   "event.outcome,map,lowercase\n",
+			err:   "unknown map mode 'lowercase'",
+		},
+		{
+			title: "unknown mode",
+			csv:   "event.outcome,replace\n",
+			err:   "unknown override mode 'replace'",
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			overrides, err := readOverrides(strings.NewReader(test.csv))
+			if test.err != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), test.err)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, overrides)
+		})
+	}
+}
+
+func TestReadMappings(t *testing.T) {
+	overrides := map[string]override{
+		"related.user": {mode: ModeAppend},
+		"host.name":    {mode: ModePrio, priorities: map[string]int{"hostname": 0, "host": 1}},
+	}
+	for _, test := range []struct {
+		title    string
+		csv      string
+		expected Mappings
+		err      string
+	}{
+		{
+			title: "valid",
+			csv: "revision,a,b\n" +
+				mappingRow("username", "Text", "user.name", "related.user", "rsa.misc.username") +
+				mappingRow("host", "Text", "host.name", "", "") +
+				mappingRow("hostname", "Text", "host.name", "", "") +
+				mappingRow("saddr", "IPv4", "source.ip", "", "") +
+				mappingRow("bytes", "UInt64", "", "", "rsa.misc.bytes") +
+				mappingRow("unused", "UInt16", "", "", ""),
+			expected: Mappings{
+				ECS: FieldMappings{
+					"username": {To: []Target{
+						{Field: "user.name", Mode: ModeSet},
+						{Field: "related.user", Mode: ModeAppend},
+					}},
+					"host":     {To: []Target{{Field: "host.name", Mode: ModePrio, Prio: 1}}},
+					"hostname": {To: []Target{{Field: "host.name", Mode: ModePrio}}},
+					"saddr": {Convert: ConvertIP, To: []Target{
+						{Field: "source.ip", Mode: ModeSet},
+						{Field: "related.ip", Mode: ModeAppend},
+					}},
+				},
+				RSA: FieldMappings{
+					"username": {To: []Target{{Field: "rsa.misc.username", Mode: ModeSet}}},
+					"bytes":    {Convert: ConvertLong, To: []Target{{Field: "rsa.misc.bytes", Mode: ModeSet}}},
+				},
+				Types: map[string]Conversion{
+					"saddr": ConvertIP,
+					"bytes": ConvertLong,
+				},
+			},
+		},
+		{
+			title: "bad type",
+			csv:   mappingRow("username", "String", "user.name", "", ""),
+			err:   "unsupported type 'String' at line 1",
+		},
+		{
+			title: "repeated",
+			csv:   mappingRow("username", "Text", "user.name", "", "") + mappingRow("username", "Text", "", "", ""),
+			err:   "repeated field 'username' at line 2",
+		},
+		{
+			title: "missing priority",
+			csv:   mappingRow("hostid", "Text", "host.name", "", ""),
+			err:   "no priority for 'hostid' in 'host.name'",
+		},
+		{
+			title: "different types",
+			csv:   mappingRow("bytes", "UInt64", "network.bytes", "", "") + mappingRow("size", "Text", "network.bytes", "", ""),
+			err:   "field network.bytes is set from different types",
+		},
+		{
+			title: "missing columns",
+			csv:   "username,Text\n",
+			err:   "wrong number of fields",
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			m, err := readMappings(strings.NewReader(test.csv), overrides)
+			if test.err != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), test.err)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, m)
+		})
+	}
+}
+
+func TestLoadMappings(t *testing.T) {
+	m, err := LoadMappings("../"+MappingsFile, "../"+MergeFile)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, m.IsSet())
+	assert.Contains(t, m.ECS, "saddr")
+	assert.Contains(t, m.RSA, "msg")
+}
@@ -20,6 +20,7 @@ import (
 
 	"github.com/pkg/errors"
 
+	"github.com/adriansr/nwdevice2filebeat/model"
 	"github.com/adriansr/nwdevice2filebeat/parser"
 	"github.com/adriansr/nwdevice2filebeat/util"
 )
@@ -40,6 +41,12 @@ const (
 	pathDelimRight = "__"
 )
 
+// Templates shared by all layouts, available as ((inline "<name>")) without
+// the template extension:
+//   - sumdata.md documents the SUMDATA definitions.
+//   - tables.md documents the INI tables that MESSAGEs are categorised in.
+var sharedTemplates = []string{"sumdata.md.tpl", "tables.md.tpl"}
+
 type Generator struct {
 	vars    Vars
 	dynVars map[string]string
@@ -63,6 +70,10 @@ type Vars struct {
 	Version       string
 	Port          uint16
 	GeneratedTime time.Time
+	// Metadata from the device INI, if any.
+	DeviceType string
+	DeviceID   string
+	Tables     []model.INITable
 }
 
 type pathReplacements map[string]string
@@ -173,10 +184,21 @@ func New(layout string, vars Vars) (*Generator, error) {
 		"getvar":  gen.doVar,
 		"setvar":  gen.doSet,
 		"title":   strings.Title,
+		"join":    strings.Join,
 		"inline":  gen.doInline,
 		"indent":  gen.doIndent,
 		"tojson":  gen.doToJSON,
 	}
+	for _, name := range sharedTemplates {
+		err = gen.AddInlineFile(strings.TrimSuffix(name, templateExt), TemplateFile{
+			Path: filepath.Join(templatesDir, name),
+			Tpl:  gen.newTemplate(),
+			Vars: vars,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
 	for _, sourcePath := range files {
 		// Strip <templatesDir>/<template> prefix from paths.
 		destPath, err := filepath.Rel(baseDir, sourcePath)
@@ -4,4 +4,16 @@ This is a module for ((.DisplayName)) logs.
 
 Autogenerated from RSA NetWitness log parser ((.LogParser.Version.Device)) XML ((.LogParser.Description.Name)) version ((.LogParser.Version.Revision))
 at ((.GeneratedTime)).
+((with .DeviceID ))
+NetWitness device ID ((.)), type (($.DeviceType)).
+(( end -))
+((with .LogParser.SumDatas ))
+### Summary data
 
+((inline "sumdata.md" -))
+(( end -))
+((with .Tables ))
+### Message tables
+
+((inline "tables.md" -))
+(( end -))
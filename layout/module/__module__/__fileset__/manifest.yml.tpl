@@ -14,6 +14,8 @@ var:
     default: true
   - name: tz_offset
     default: local
+  - name: local_networks
+    default: ""
   - name: rsa_fields
     default: true
   - name: keep_raw_fields
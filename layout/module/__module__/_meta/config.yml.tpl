@@ -17,3 +17,8 @@
     # "local" (default) for system timezone.
     # "+02:00" for GMT+02:00
     # var.tz_offset: local
+
+    # Comma-separated list of networks (CIDR) considered local when
+    # computing the network direction. Private and loopback networks
+    # are used by default.
+    # var.local_networks: "10.0.0.0/8, 192.168.0.0/16"
@@ -48,6 +48,12 @@ logs from a host on a different timezone, use this field to set the timezone
 offset so that datetimes are correctly parsed. Valid values are in the form
 ±HH:mm, for example, `-07:00` for `UTC-7`.
 
+*`var.local_networks`*::
+
+Comma-separated list of networks, in CIDR notation, that are considered local
+when determining the direction of network traffic (`network.direction`). When
+unset, private (RFC 1918 and RFC 4193) and loopback networks are used.
+
 *`var.rsa_fields`*::
 
 Flag to control the addition of non-ECS fields to the event. Defaults to true,
@@ -36,6 +36,12 @@ streams:
         required: false
         show_user: true
         default: "local"
+      - name: local_networks
+        type: text
+        title: Local networks (comma-separated CIDR list)
+        required: false
+        show_user: false
+        default: ""
       - name: rsa_fields
         type: bool
         title: Add non-ECS fields
@@ -88,6 +94,12 @@ streams:
         required: false
         show_user: true
         default: "local"
+      - name: local_networks
+        type: text
+        title: Local networks (comma-separated CIDR list)
+        required: false
+        show_user: false
+        default: ""
       - name: rsa_fields
         type: bool
         title: Add non-ECS fields
@@ -134,6 +146,12 @@ streams:
         required: false
         show_user: true
         default: "local"
+      - name: local_networks
+        type: text
+        title: Local networks (comma-separated CIDR list)
+        required: false
+        show_user: false
+        default: ""
       - name: rsa_fields
         type: bool
         title: Add non-ECS fields
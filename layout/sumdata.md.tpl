@@ -0,0 +1,12 @@
+The original log parser defines the following event summarisation buckets.
+Each one can be reproduced in Kibana with a terms aggregation on the key field
+(and subkey, when present). Keys are the parser's own field names, before
+conversion to ECS, or NetWitness variables such as `$DEVICE`. Fields are the
+values accumulated in each bucket, as listed in the log parser: either field
+names or numeric placeholders (such as `0,0,1`) that don't refer to any field.
+
+| Bucket | Key | Subkey | Fields |
+|---|---|---|---|
+(( range .LogParser.SumDatas -))
+| ((.Bucket)) | ((.Key)) | ((.SubKey)) | ((join .Fields ", ")) |
+(( end -))
@@ -0,0 +1,9 @@
+The original log parser assigns its messages to the following reporting
+tables, as defined by the `tableid` of each message in the log parser and the
+table list of the device.
+
+| ID | Table | Name |
+|---|---|---|
+(( range .Tables -))
+| ((.ID)) | ((.RealTable)) | ((.Name)) |
+(( end -))
@@ -20,9 +20,11 @@ import (
 )
 
 type Device struct {
-	XMLPath     string
-	Description DeviceHeader
-	Version     Version
+	XMLPath        string
+	CustomXMLPaths []string
+	INI            *DeviceINI
+	Description    DeviceHeader
+	Version        Version
 
 	Headers    []*Header
 	Messages   []*Message
@@ -33,8 +35,23 @@ type Device struct {
 	SumDatas   []*SumData
 }
 
+// LoadOptions controls how a device is loaded.
+type LoadOptions struct {
+	// Lenient enables recovery from errors in the XML: Elements that can't be
+	// decoded are skipped, unknown attributes are ignored and the U+0092
+	// codepoint is replaced. All of those are reported as warnings.
+	// Syntax errors are still fatal as the XML decoder can't recover from them.
+	Lenient bool
+}
+
 // New turns a new Device from the given directory path.
-func NewDevice(path string, _ *util.Warnings) (Device, error) {
+func NewDevice(path string, warnings *util.Warnings) (Device, error) {
+	return NewDeviceWithOptions(path, LoadOptions{}, warnings)
+}
+
+// NewDeviceWithOptions returns a new Device from the given directory path
+// using the given options.
+func NewDeviceWithOptions(path string, opts LoadOptions, warnings *util.Warnings) (Device, error) {
 	files, err := util.ListFiles(path)
 	if err != nil {
 		return Device{}, err
@@ -43,8 +60,16 @@ func NewDevice(path string, _ *util.Warnings) (Device, error) {
 	byExt := util.ByExtension(files)
 	log.Printf("Found files: %+v", byExt)
 
-	xmlFiles := byExt[".xml"]
-	// Device log parser dirs only contain one XML.
+	// Device log parser dirs contain one base XML and, optionally, custom
+	// XMLs that extend it.
+	var xmlFiles, customFiles util.FileList
+	for _, path := range byExt[".xml"] {
+		if IsCustomXML(path) {
+			customFiles = append(customFiles, path)
+		} else {
+			xmlFiles = append(xmlFiles, path)
+		}
+	}
 	switch len(xmlFiles) {
 	case 0:
 		return Device{}, errors.Errorf("device path doesn't contain a parser definition (no XML file found)")
@@ -56,9 +81,31 @@ func NewDevice(path string, _ *util.Warnings) (Device, error) {
 	dev := Device{
 		XMLPath: xmlFiles[0],
 	}
-	if err = dev.load(); err != nil {
+	if err = dev.load(opts, warnings); err != nil {
 		return dev, err
 	}
+	for _, path := range customFiles {
+		custom := Device{
+			XMLPath: path,
+		}
+		if err = custom.load(opts, warnings); err != nil {
+			return dev, errors.Wrapf(err, "failed loading custom XML %s", path)
+		}
+		dev.Merge(custom, warnings)
+		dev.CustomXMLPaths = append(dev.CustomXMLPaths, path)
+	}
+
+	// The INI file is optional, it only provides metadata about the device.
+	iniFiles := byExt[".ini"]
+	switch len(iniFiles) {
+	case 0:
+	case 1:
+		if dev.INI, err = LoadDeviceINI(iniFiles[0], warnings); err != nil {
+			return dev, errors.Wrap(err, "failed loading device INI")
+		}
+	default:
+		warnings.Addf(util.XMLPos{Path: path}, "ignoring INI files as more than one was found: %+v", iniFiles)
+	}
 	return dev, nil
 }
 
@@ -67,17 +114,20 @@ func NewDeviceFromXML(path string) (Device, error) {
 	dev := Device{
 		XMLPath: path,
 	}
-	if err := dev.load(); err != nil {
+	if err := dev.load(LoadOptions{}, nil); err != nil {
 		return dev, err
 	}
 	return dev, nil
 }
 
 func (dev *Device) String() string {
+	if len(dev.CustomXMLPaths) > 0 {
+		return fmt.Sprintf("device={%s, %s, xml:'%s', custom:%v}", dev.Description.String(), dev.Version.String(), dev.XMLPath, dev.CustomXMLPaths)
+	}
 	return fmt.Sprintf("device={%s, %s, xml:'%s'}", dev.Description.String(), dev.Version.String(), dev.XMLPath)
 }
 
-func (dev *Device) load() error {
+func (dev *Device) load(opts LoadOptions, warnings *util.Warnings) error {
 	fHandle, err := os.Open(dev.XMLPath)
 	if err != nil {
 		return err
@@ -92,8 +142,6 @@ func (dev *Device) load() error {
 	}
 	lineReader := util.NewLineReader(fileReader)
 	decoder := xml.NewDecoder(lineReader)
-	// TODO: Config flag
-	decoder.Strict = true
 	// Support custom charset inside XML.
 	decoder.CharsetReader = charset.NewReaderLabel
 
@@ -115,8 +163,13 @@ func (dev *Device) load() error {
 			return errors.Wrapf(err, "error reading at %s", pos)
 		}
 		if isBadEncoding(token) {
-			return errors.Errorf("error decoding at %s: Invalid codepoint (U+0092) detected. This is usually caused by using the &#x092 XML entity in place of a Right Single Quotation Mark. Edit the pipeline to remove this codepoint.",
-				pos)
+			if opts.Lenient {
+				token = fixBadEncoding(token)
+				warnings.Addf(pos, "replaced invalid codepoint U+0092 with U+2019 (Right Single Quotation Mark)")
+			} else {
+				return errors.Errorf("error decoding at %s: Invalid codepoint (U+0092) detected. This is usually caused by using the &#x092 XML entity in place of a Right Single Quotation Mark. Edit the pipeline to remove this codepoint.",
+					pos)
+			}
 		}
 		fn, exists := xmlStates[state]
 		if !exists {
@@ -125,13 +178,30 @@ func (dev *Device) load() error {
 		var item XMLElement
 		item, state, err = fn(token, decoder)
 		if err != nil {
-			return errors.Wrapf(err, "error decoding at %s", pos)
+			elemErr, ok := errors.Cause(err).(*elementError)
+			if !opts.Lenient || !ok {
+				return errors.Wrapf(err, "error decoding at %s", pos)
+			}
+			if elemErr.skip {
+				if err = decoder.Skip(); err != nil {
+					return errors.Wrapf(err, "error skipping element at %s", pos)
+				}
+			}
+			if elemErr.item == nil {
+				warnings.Addf(pos, "skipped element: %v", elemErr.err)
+			} else {
+				warnings.Addf(pos, "ignored: %v", elemErr.err)
+			}
+			item, state = elemErr.item, elemErr.next
 		}
 		if item != nil {
 			numItems++
 			item.SetPos(pos)
 			if err = item.Apply(dev); err != nil {
-				return errors.Wrapf(err, "error applying item at %s", pos)
+				if !opts.Lenient {
+					return errors.Wrapf(err, "error applying item at %s", pos)
+				}
+				warnings.Addf(pos, "skipped element: %v", err)
 			}
 		}
 	}
@@ -174,8 +244,43 @@ func isBadEncoding(token xml.Token) bool {
 	return false
 }
 
+// fixBadEncoding replaces U+0092 with U+2019 in the given token.
+func fixBadEncoding(token xml.Token) xml.Token {
+	fix := func(s string) string {
+		return strings.Replace(s, "\u0092", "\u2019", -1)
+	}
+	switch v := token.(type) {
+	case xml.StartElement:
+		attrs := make([]xml.Attr, len(v.Attr))
+		for idx, attr := range v.Attr {
+			attrs[idx] = xml.Attr{Name: attr.Name, Value: fix(attr.Value)}
+		}
+		v.Attr = attrs
+		return v
+	case xml.CharData:
+		return xml.CharData(fix(string(v)))
+	}
+	return token
+}
+
 type stateFn func(token xml.Token, decoder *xml.Decoder) (XMLElement, xmlState, error)
 
+// elementError is an error affecting a single element, which can be
+// recovered from in lenient mode by continuing at the next state.
+type elementError struct {
+	err error
+	// item, if set, is the element to keep despite the error.
+	item XMLElement
+	// next is the state to continue from.
+	next xmlState
+	// skip is set when the element wasn't consumed from the decoder.
+	skip bool
+}
+
+func (e *elementError) Error() string {
+	return e.err.Error()
+}
+
 func name(n string) xml.Name {
 	return xml.Name{
 		Local: n,
@@ -316,6 +421,9 @@ func (v *Version) Apply(dev *Device) error {
 
 type TagValMap struct {
 	XMLBaseElement
+	// Name is used to select the TAGVALMAP from a MESSAGE when the device
+	// defines more than one. Not part of the NetWitness format.
+	Name              string `xml:"name,attr"`
 	Delimiter         string `xml:"delimiter,attr"`
 	ValueDelimiter    string `xml:"valuedelimiter,attr"`
 	PairDelimiter     string `xml:"pairdelimiter,attr"`
@@ -355,6 +463,9 @@ type Message struct {
 	ParseDefValue string `xml:"parsedefvalue,attr"`
 	TableID       string `xml:"tableid,attr"`
 	Summary       string `xml:"summary,attr"`
+	// TagValMap is the name of the TAGVALMAP used by a tagval message. Not
+	// part of the NetWitness format.
+	TagValMap string `xml:"tagvalmap,attr"`
 }
 
 func (m *Message) Apply(dev *Device) error {
@@ -416,14 +527,28 @@ func stateBody(token xml.Token, decoder *xml.Decoder) (XMLElement, xmlState, err
 	case xml.StartElement:
 		alloc, ok := allowedBodyTags[v.Name]
 		if !ok {
-			return nil, xmlStateErr, errors.Errorf("unexpected XML tag found: %s", displayName(v.Name))
+			return nil, xmlStateErr, &elementError{
+				err:  errors.Errorf("unexpected XML tag found: %s", displayName(v.Name)),
+				next: xmlStateBody,
+				skip: true,
+			}
 		}
 		e := alloc()
 		if err := decoder.DecodeElement(&e, &v); err != nil {
-			return nil, xmlStateErr, errors.Wrapf(err, "error decoding tag %s", displayName(v.Name))
+			if _, isSyntax := err.(*xml.SyntaxError); isSyntax {
+				return nil, xmlStateErr, errors.Wrapf(err, "error decoding tag %s", displayName(v.Name))
+			}
+			return nil, xmlStateErr, &elementError{
+				err:  errors.Wrapf(err, "error decoding tag %s", displayName(v.Name)),
+				next: xmlStateBody,
+			}
 		}
 		if err := e.XMLDecodingError(); err != nil {
-			return nil, xmlStateErr, errors.Wrapf(err, "unexpected data decoding tag %s", displayName(v.Name))
+			return nil, xmlStateErr, &elementError{
+				err:  errors.Wrapf(err, "unexpected data decoding tag %s", displayName(v.Name)),
+				item: e,
+				next: xmlStateBody,
+			}
 		}
 		return e, xmlStateBody, nil
 
@@ -452,6 +577,7 @@ func stateDeviceMessages(token xml.Token, _ *xml.Decoder) (XMLElement, xmlState,
 			return nil, xmlStateErr, errors.Errorf("unexpected tag:%v found. Expected:%v", v.Name, expected)
 		}
 		var dm DeviceHeader
+		var unknown []string
 		// Manual decoding :(
 		// There is no way to decode this element using standard library without
 		// decoding all the inner XML (messages, headers, etc.)
@@ -464,7 +590,14 @@ func stateDeviceMessages(token xml.Token, _ *xml.Decoder) (XMLElement, xmlState,
 			case "group":
 				dm.Group = attr.Value
 			default:
-				return nil, xmlStateErr, errors.Errorf("unexpected attribute in %s: %s", displayName(expected), name)
+				unknown = append(unknown, name)
+			}
+		}
+		if len(unknown) > 0 {
+			return nil, xmlStateErr, &elementError{
+				err:  errors.Errorf("unexpected attribute in %s: %s", displayName(expected), strings.Join(unknown, ", ")),
+				item: &dm,
+				next: xmlStateBody,
 			}
 		}
 		return &dm, xmlStateBody, nil
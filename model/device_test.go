@@ -0,0 +1,139 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package model
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adriansr/nwdevice2filebeat/util"
+)
+
+func loadXML(t *testing.T, data string, opts LoadOptions, warnings *util.Warnings) (Device, error) {
+	dir, err := ioutil.TempDir("", "nwdevice_load")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "device.xml")
+	if !assert.NoError(t, ioutil.WriteFile(path, []byte(data), 0644)) {
+		t.FailNow()
+	}
+	return NewDeviceWithOptions(dir, opts, warnings)
+}
+
+func TestLoadLenient(t *testing.T) {
+	for _, testCase := range []struct {
+		title    string
+		xml      string
+		headers  []string
+		messages []string
+		warnings []string
+	}{
+		{
+			title: "unknown tag",
+			xml: `<?xml version="1.0" encoding="ISO-8859-1"?>
+<DEVICEMESSAGES name="test">
+<HEADER id1="h1" id2="h1" content="&lt;messageid&gt; &lt;!payload&gt;"/>
+<RULE id="1"><MESSAGE id1="inner" id2="inner" content="x"/></RULE>
+<MESSAGE id1="m1" id2="m1" content="a"/>
+</DEVICEMESSAGES>`,
+			headers:  []string{"h1"},
+			messages: []string{"m1"},
+			warnings: []string{"skipped element: unexpected XML tag found: RULE"},
+		},
+		{
+			title: "unknown attributes",
+			xml: `<?xml version="1.0" encoding="ISO-8859-1"?>
+<DEVICEMESSAGES name="test" vendor="acme">
+<MESSAGE id1="m1" id2="m1" content="a" color="blue"/>
+</DEVICEMESSAGES>`,
+			messages: []string{"m1"},
+			warnings: []string{
+				"ignored: unexpected attribute in DEVICEMESSAGES: vendor",
+				`ignored: unexpected data decoding tag MESSAGE: unknown attributes=["color"]`,
+			},
+		},
+		{
+			title: "bad codepoint",
+			xml: `<?xml version="1.0" encoding="UTF-8"?>
+<DEVICEMESSAGES name="test">
+<MESSAGE id1="m1" id2="m1" content="can&#x92;t"/>
+</DEVICEMESSAGES>`,
+			messages: []string{"m1"},
+			warnings: []string{"replaced invalid codepoint U+0092 with U+2019 (Right Single Quotation Mark)"},
+		},
+		{
+			title: "duplicate version",
+			xml: `<?xml version="1.0" encoding="ISO-8859-1"?>
+<DEVICEMESSAGES name="test">
+<VERSION revision="1"/>
+<VERSION revision="2"/>
+<MESSAGE id1="m1" id2="m1" content="a"/>
+</DEVICEMESSAGES>`,
+			messages: []string{"m1"},
+			warnings: []string{"skipped element: VERSION already set from "},
+		},
+	} {
+		t.Run(testCase.title, func(t *testing.T) {
+			_, err := loadXML(t, testCase.xml, LoadOptions{}, nil)
+			assert.Error(t, err, "strict mode must fail")
+
+			warnings := util.NewWarnings(10)
+			dev, err := loadXML(t, testCase.xml, LoadOptions{Lenient: true}, &warnings)
+			if !assert.NoError(t, err) {
+				return
+			}
+			var headers, messages []string
+			for _, h := range dev.Headers {
+				headers = append(headers, h.ID1)
+			}
+			for _, m := range dev.Messages {
+				messages = append(messages, m.ID1)
+			}
+			assert.Equal(t, testCase.headers, headers)
+			assert.Equal(t, testCase.messages, messages)
+			if assert.Len(t, warnings.Message, len(testCase.warnings)) {
+				for idx, expected := range testCase.warnings {
+					assert.Contains(t, warnings.Message[idx].Text, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadLenientFixesCodepoint(t *testing.T) {
+	dev, err := loadXML(t, `<?xml version="1.0" encoding="UTF-8"?>
+<DEVICEMESSAGES name="test">
+<MESSAGE id1="m1" id2="m1" content="can&#x92;t"/>
+</DEVICEMESSAGES>`, LoadOptions{Lenient: true}, nil)
+	if !assert.NoError(t, err) || !assert.Len(t, dev.Messages, 1) {
+		return
+	}
+	assert.Equal(t, "can’t", dev.Messages[0].Content)
+}
+
+func TestLoadLenientSyntaxError(t *testing.T) {
+	for _, xml := range []string{
+		// HTML entities are not valid XML.
+		`<?xml version="1.0" encoding="UTF-8"?>
+<DEVICEMESSAGES name="test">
+<MESSAGE id1="m1" id2="m1" content="a&nbsp;b"/>
+</DEVICEMESSAGES>`,
+		// Unquoted attribute.
+		`<?xml version="1.0" encoding="UTF-8"?>
+<DEVICEMESSAGES name="test">
+<MESSAGE id1=m1 id2="m1" content="a"/>
+</DEVICEMESSAGES>`,
+	} {
+		warnings := util.NewWarnings(10)
+		_, err := loadXML(t, xml, LoadOptions{Lenient: true}, &warnings)
+		assert.Error(t, err)
+	}
+}
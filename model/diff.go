@@ -0,0 +1,233 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package model
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Kinds of change reported by Diff.
+const (
+	Added    = "added"
+	Removed  = "removed"
+	Modified = "modified"
+)
+
+// DeviceDiff holds the differences between two revisions of a device.
+type DeviceDiff struct {
+	OldRevision string          `json:"old_revision"`
+	NewRevision string          `json:"new_revision"`
+	Headers     []ElementChange `json:"headers,omitempty"`
+	Messages    []ElementChange `json:"messages,omitempty"`
+	ValueMaps   []ElementChange `json:"valuemaps,omitempty"`
+	TagValMaps  []ElementChange `json:"tagvalmaps,omitempty"`
+}
+
+// ElementChange is an element that was added, removed or modified.
+type ElementChange struct {
+	Change     string        `json:"change"`
+	ID         string        `json:"id"`
+	Attributes []ValueChange `json:"attributes,omitempty"`
+	// Entries lists the changed key-value pairs of a VALUEMAP.
+	Entries []ValueChange `json:"entries,omitempty"`
+}
+
+// ValueChange is an attribute or entry whose value changed.
+// Old is empty for added values and New is empty for removed ones.
+type ValueChange struct {
+	Name string `json:"name"`
+	Old  string `json:"old,omitempty"`
+	New  string `json:"new,omitempty"`
+}
+
+// Empty returns true when no changes were found.
+func (d *DeviceDiff) Empty() bool {
+	return len(d.Headers)+len(d.Messages)+len(d.ValueMaps)+len(d.TagValMaps) == 0
+}
+
+// Diff compares two devices. HEADERs and MESSAGEs are identified by their
+// id1 attribute, VALUEMAPs by their name and TAGVALMAPs by their position.
+func Diff(old, updated *Device) (d DeviceDiff) {
+	d.OldRevision = old.Version.Revision
+	d.NewRevision = updated.Version.Revision
+
+	oldHeaders, newHeaders := make([]keyedElement, len(old.Headers)), make([]keyedElement, len(updated.Headers))
+	for idx, h := range old.Headers {
+		oldHeaders[idx] = keyedElement{h.ID1, h}
+	}
+	for idx, h := range updated.Headers {
+		newHeaders[idx] = keyedElement{h.ID1, h}
+	}
+	d.Headers = diffElements(oldHeaders, newHeaders, nil)
+
+	oldMsgs, newMsgs := make([]keyedElement, len(old.Messages)), make([]keyedElement, len(updated.Messages))
+	for idx, m := range old.Messages {
+		oldMsgs[idx] = keyedElement{m.ID1, m}
+	}
+	for idx, m := range updated.Messages {
+		newMsgs[idx] = keyedElement{m.ID1, m}
+	}
+	d.Messages = diffElements(oldMsgs, newMsgs, nil)
+
+	oldVMs, newVMs := make([]keyedElement, len(old.ValueMaps)), make([]keyedElement, len(updated.ValueMaps))
+	for idx, vm := range old.ValueMaps {
+		oldVMs[idx] = keyedElement{vm.Name, vm}
+	}
+	for idx, vm := range updated.ValueMaps {
+		newVMs[idx] = keyedElement{vm.Name, vm}
+	}
+	d.ValueMaps = diffElements(oldVMs, newVMs, func(change *ElementChange, a, b XMLElement) {
+		oldEntries := valueMapEntries(a.(*ValueMap).KeyValuePairs)
+		newEntries := valueMapEntries(b.(*ValueMap).KeyValuePairs)
+		change.Entries = diffValues(oldEntries, newEntries)
+		// Entries are reported individually.
+		var attrs []ValueChange
+		for _, attr := range change.Attributes {
+			if attr.Name != "keyvaluepairs" {
+				attrs = append(attrs, attr)
+			}
+		}
+		change.Attributes = attrs
+	})
+
+	oldTVMs, newTVMs := make([]keyedElement, len(old.TagValMaps)), make([]keyedElement, len(updated.TagValMaps))
+	for idx, tvm := range old.TagValMaps {
+		oldTVMs[idx] = keyedElement{tagValMapKey(idx, tvm), tvm}
+	}
+	for idx, tvm := range updated.TagValMaps {
+		newTVMs[idx] = keyedElement{tagValMapKey(idx, tvm), tvm}
+	}
+	d.TagValMaps = diffElements(oldTVMs, newTVMs, nil)
+	return d
+}
+
+// WriteText writes a human-readable report of the differences.
+func (d *DeviceDiff) WriteText(w io.Writer) {
+	if d.OldRevision != d.NewRevision {
+		fmt.Fprintf(w, "Revision: %s -> %s\n", d.OldRevision, d.NewRevision)
+	}
+	if d.Empty() {
+		fmt.Fprintln(w, "No changes.")
+		return
+	}
+	for _, section := range []struct {
+		name    string
+		changes []ElementChange
+	}{
+		{"HEADER", d.Headers},
+		{"MESSAGE", d.Messages},
+		{"VALUEMAP", d.ValueMaps},
+		{"TAGVALMAP", d.TagValMaps},
+	} {
+		for _, change := range section.changes {
+			fmt.Fprintf(w, "%s %s: %s\n", section.name, change.ID, change.Change)
+			for _, attr := range change.Attributes {
+				fmt.Fprintf(w, "    %s: %s\n", attr.Name, attr.describe())
+			}
+			for _, entry := range change.Entries {
+				fmt.Fprintf(w, "    entry %s: %s\n", entry.Name, entry.describe())
+			}
+		}
+	}
+}
+
+func (c ValueChange) describe() string {
+	switch {
+	case c.Old == "":
+		return fmt.Sprintf("added %q", c.New)
+	case c.New == "":
+		return fmt.Sprintf("removed %q", c.Old)
+	default:
+		return fmt.Sprintf("%q -> %q", c.Old, c.New)
+	}
+}
+
+// tagValMapKey identifies a TAGVALMAP by its name, or by its position when
+// it has none.
+func tagValMapKey(idx int, tvm *TagValMap) string {
+	if tvm.Name != "" {
+		return tvm.Name
+	}
+	return strconv.Itoa(idx)
+}
+
+type keyedElement struct {
+	key  string
+	elem XMLElement
+}
+
+// diffElements compares two lists of elements. Removed and modified elements
+// are reported in the old order, followed by added elements in the new order.
+func diffElements(old, updated []keyedElement, modified func(*ElementChange, XMLElement, XMLElement)) (changes []ElementChange) {
+	newByKey := make(map[string]XMLElement, len(updated))
+	for _, e := range updated {
+		newByKey[e.key] = e.elem
+	}
+	oldByKey := make(map[string]bool, len(old))
+	for _, e := range old {
+		oldByKey[e.key] = true
+		newElem, found := newByKey[e.key]
+		if !found {
+			changes = append(changes, ElementChange{Change: Removed, ID: e.key})
+			continue
+		}
+		change := ElementChange{
+			Change:     Modified,
+			ID:         e.key,
+			Attributes: diffValues(elementAttrs(e.elem), elementAttrs(newElem)),
+		}
+		if modified != nil {
+			modified(&change, e.elem, newElem)
+		}
+		if len(change.Attributes)+len(change.Entries) > 0 {
+			changes = append(changes, change)
+		}
+	}
+	for _, e := range updated {
+		if !oldByKey[e.key] {
+			changes = append(changes, ElementChange{Change: Added, ID: e.key})
+		}
+	}
+	return changes
+}
+
+// diffValues compares two lists of (name, value) pairs.
+func diffValues(old, updated [][2]string) (changes []ValueChange) {
+	newValues := make(map[string]string, len(updated))
+	for _, kv := range updated {
+		newValues[kv[0]] = kv[1]
+	}
+	oldValues := make(map[string]bool, len(old))
+	for _, kv := range old {
+		oldValues[kv[0]] = true
+		if value := newValues[kv[0]]; value != kv[1] {
+			changes = append(changes, ValueChange{Name: kv[0], Old: kv[1], New: value})
+		}
+	}
+	for _, kv := range updated {
+		if !oldValues[kv[0]] {
+			changes = append(changes, ValueChange{Name: kv[0], New: kv[1]})
+		}
+	}
+	return changes
+}
+
+// valueMapEntries splits the keyvaluepairs attribute of a VALUEMAP.
+func valueMapEntries(kvpairs string) (entries [][2]string) {
+	for _, pair := range strings.Split(kvpairs, "|") {
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			kv = append(kv, "")
+		}
+		entries = append(entries, [2]string{kv[0], kv[1]})
+	}
+	return entries
+}
@@ -0,0 +1,121 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	for _, testCase := range []struct {
+		title    string
+		old, new Device
+		expected DeviceDiff
+		text     string
+	}{
+		{
+			title: "no changes",
+			old: Device{
+				Version: Version{Revision: "1"},
+				Headers: []*Header{{ID1: "h1", Content: "a"}},
+			},
+			new: Device{
+				Version: Version{Revision: "1"},
+				Headers: []*Header{{ID1: "h1", Content: "a"}},
+			},
+			expected: DeviceDiff{OldRevision: "1", NewRevision: "1"},
+			text:     "No changes.\n",
+		},
+		{
+			title: "headers and messages",
+			old: Device{
+				Version: Version{Revision: "1"},
+				Headers: []*Header{
+					{ID1: "h1", Content: "a"},
+					{ID1: "h2", Content: "b"},
+				},
+				Messages: []*Message{
+					{ID1: "m1", ID2: "x", Content: "c"},
+				},
+			},
+			new: Device{
+				Version: Version{Revision: "2"},
+				Headers: []*Header{
+					{ID1: "h1", Content: "a2", Functions: "<@msg:*PARMVAL($MSG)>"},
+				},
+				Messages: []*Message{
+					{ID1: "m1", ID2: "x", Content: "c"},
+					{ID1: "m2", ID2: "x", Content: "d"},
+				},
+			},
+			expected: DeviceDiff{
+				OldRevision: "1",
+				NewRevision: "2",
+				Headers: []ElementChange{
+					{Change: Modified, ID: "h1", Attributes: []ValueChange{
+						{Name: "content", Old: "a", New: "a2"},
+						{Name: "functions", New: "<@msg:*PARMVAL($MSG)>"},
+					}},
+					{Change: Removed, ID: "h2"},
+				},
+				Messages: []ElementChange{
+					{Change: Added, ID: "m2"},
+				},
+			},
+			text: "Revision: 1 -> 2\n" +
+				"HEADER h1: modified\n" +
+				"    content: \"a\" -> \"a2\"\n" +
+				"    functions: added \"<@msg:*PARMVAL($MSG)>\"\n" +
+				"HEADER h2: removed\n" +
+				"MESSAGE m2: added\n",
+		},
+		{
+			title: "valuemaps and tagvalmaps",
+			old: Device{
+				ValueMaps: []*ValueMap{
+					{Name: "vm", Default: "$NONE", KeyValuePairs: "'1'='one'|'2'='two'|'3'='three'"},
+				},
+				TagValMaps: []*TagValMap{{Delimiter: "|", PairDelimiter: "="}},
+			},
+			new: Device{
+				ValueMaps: []*ValueMap{
+					{Name: "vm", Default: "$NONE", KeyValuePairs: "'1'='one'|'2'='TWO'|'4'='four'"},
+				},
+				TagValMaps: []*TagValMap{{Delimiter: ";", PairDelimiter: "="}},
+			},
+			expected: DeviceDiff{
+				ValueMaps: []ElementChange{
+					{Change: Modified, ID: "vm", Entries: []ValueChange{
+						{Name: "'2'", Old: "'two'", New: "'TWO'"},
+						{Name: "'3'", Old: "'three'"},
+						{Name: "'4'", New: "'four'"},
+					}},
+				},
+				TagValMaps: []ElementChange{
+					{Change: Modified, ID: "0", Attributes: []ValueChange{
+						{Name: "delimiter", Old: "|", New: ";"},
+					}},
+				},
+			},
+			text: "VALUEMAP vm: modified\n" +
+				"    entry '2': \"'two'\" -> \"'TWO'\"\n" +
+				"    entry '3': removed \"'three'\"\n" +
+				"    entry '4': added \"'four'\"\n" +
+				"TAGVALMAP 0: modified\n" +
+				"    delimiter: \"|\" -> \";\"\n",
+		},
+	} {
+		t.Run(testCase.title, func(t *testing.T) {
+			diff := Diff(&testCase.old, &testCase.new)
+			assert.Equal(t, testCase.expected, diff)
+			var sb strings.Builder
+			diff.WriteText(&sb)
+			assert.Equal(t, testCase.text, sb.String())
+		})
+	}
+}
@@ -0,0 +1,151 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package model
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/adriansr/nwdevice2filebeat/util"
+)
+
+// DeviceINI holds the device settings found in the .ini file that is shipped
+// alongside the device XML.
+type DeviceINI struct {
+	Path         string
+	DatabaseName string
+	DisplayName  string
+	IniFlagName  string
+	ImageFile    string
+	DeviceType   string
+	DeviceGroup  string
+	DeviceID     string
+
+	// Tables is the list of tables (TableListN/RealTableN pairs) referenced
+	// by the tableid attribute of MESSAGEs, indexed by their ID.
+	Tables map[int]INITable
+
+	// Values contains all the key-value pairs found in the file.
+	Values map[string]string
+}
+
+// INITable is a table definition from the INI file.
+type INITable struct {
+	ID        int
+	Name      string
+	RealTable string
+}
+
+// Table returns the table referenced by a MESSAGE tableid.
+func (ini *DeviceINI) Table(id string) (table INITable, found bool) {
+	if ini == nil {
+		return table, false
+	}
+	num, err := strconv.Atoi(strings.TrimSpace(id))
+	if err != nil {
+		return table, false
+	}
+	table, found = ini.Tables[num]
+	return table, found
+}
+
+// Vendor returns the vendor name from the display name, which for most
+// devices is in the form "<Vendor> <Product>" (i.e. "Cisco PIX Firewall").
+// Returns an empty string when the display name is a single word, as the INI
+// has no setting for the vendor.
+func (ini *DeviceINI) Vendor() string {
+	if ini == nil {
+		return ""
+	}
+	words := strings.Fields(ini.DisplayName)
+	if len(words) < 2 {
+		return ""
+	}
+	return words[0]
+}
+
+// LoadDeviceINI parses the INI file at the given path.
+func LoadDeviceINI(path string, warnings *util.Warnings) (*DeviceINI, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readDeviceINI(f, path, warnings)
+}
+
+func readDeviceINI(r io.Reader, path string, warnings *util.Warnings) (*DeviceINI, error) {
+	ini := &DeviceINI{
+		Path:   path,
+		Tables: make(map[int]INITable),
+		Values: make(map[string]string),
+	}
+	pos := util.XMLPos{
+		Path: path,
+		Col:  1,
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		pos.Line++
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+		eq := strings.IndexByte(line, '=')
+		if eq <= 0 {
+			warnings.Addf(pos, "ignored malformed INI line: '%s'", line)
+			continue
+		}
+		key, value := strings.TrimSpace(line[:eq]), strings.TrimSpace(line[eq+1:])
+		if prev, found := ini.Values[key]; found && prev != value {
+			warnings.Addf(pos, "INI key %s redefined (old value: '%s', new value: '%s')", key, prev, value)
+		}
+		ini.Values[key] = value
+		switch key {
+		case "DatabaseName":
+			ini.DatabaseName = value
+		case "DisplayName":
+			ini.DisplayName = value
+		case "IniFlagName":
+			ini.IniFlagName = value
+		case "ImageFile":
+			ini.ImageFile = value
+		case "DeviceType":
+			ini.DeviceType = value
+		case "DeviceGroup":
+			ini.DeviceGroup = value
+		case "DeviceID":
+			ini.DeviceID = value
+		default:
+			if id, ok := indexedKey(key, "TableList"); ok {
+				table := ini.Tables[id]
+				table.ID, table.Name = id, value
+				ini.Tables[id] = table
+			} else if id, ok := indexedKey(key, "RealTable"); ok {
+				table := ini.Tables[id]
+				table.ID, table.RealTable = id, value
+				ini.Tables[id] = table
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "error reading INI file %s", path)
+	}
+	return ini, nil
+}
+
+// indexedKey parses keys in the form <prefix><number>.
+func indexedKey(key, prefix string) (id int, ok bool) {
+	if !strings.HasPrefix(key, prefix) {
+		return 0, false
+	}
+	id, err := strconv.Atoi(key[len(prefix):])
+	return id, err == nil
+}
@@ -0,0 +1,119 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adriansr/nwdevice2filebeat/util"
+)
+
+func TestReadDeviceINI(t *testing.T) {
+	for _, testCase := range []struct {
+		title    string
+		data     string
+		expected DeviceINI
+		warnings int
+	}{
+		{
+			title: "basic",
+			data: "DatabaseName=squid\r\n" +
+				"DisplayName=Squid\r\n" +
+				"DeviceType=166\r\n" +
+				"DeviceGroup=Web Logs\r\n" +
+				"DeviceID=U166\r\n" +
+				"TableList1=FireWall Level\r\n" +
+				"TableList77=Firewall\r\n" +
+				"RealTable1=level\r\n" +
+				"RealTable77=firewall\r\n" +
+				"SumTable1=sumconnaddr\r\n",
+			expected: DeviceINI{
+				DatabaseName: "squid",
+				DisplayName:  "Squid",
+				DeviceType:   "166",
+				DeviceGroup:  "Web Logs",
+				DeviceID:     "U166",
+				Tables: map[int]INITable{
+					1:  {ID: 1, Name: "FireWall Level", RealTable: "level"},
+					77: {ID: 77, Name: "Firewall", RealTable: "firewall"},
+				},
+			},
+		},
+		{
+			title: "comments and malformed lines",
+			data: "; comment\n" +
+				"\n" +
+				"DisplayName = Cisco ASA \n" +
+				"garbage\n" +
+				"=value\n" +
+				"TableListX=ignored\n",
+			expected: DeviceINI{
+				DisplayName: "Cisco ASA",
+				Tables:      map[int]INITable{},
+			},
+			warnings: 2,
+		},
+		{
+			title: "redefined key",
+			data: "DeviceGroup=Firewall\n" +
+				"DeviceGroup=IDS\n",
+			expected: DeviceINI{
+				DeviceGroup: "IDS",
+				Tables:      map[int]INITable{},
+			},
+			warnings: 1,
+		},
+	} {
+		t.Run(testCase.title, func(t *testing.T) {
+			warnings := util.NewWarnings(10)
+			ini, err := readDeviceINI(strings.NewReader(testCase.data), "test.ini", &warnings)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, "test.ini", ini.Path)
+			ini.Path, ini.Values = "", nil
+			assert.Equal(t, testCase.expected, *ini)
+			assert.Equal(t, testCase.warnings, warnings.Total, warnings.Message)
+		})
+	}
+}
+
+func TestDeviceINI_Table(t *testing.T) {
+	ini := &DeviceINI{
+		Tables: map[int]INITable{
+			77: {ID: 77, Name: "Firewall", RealTable: "firewall"},
+		},
+	}
+	table, found := ini.Table("77")
+	assert.True(t, found)
+	assert.Equal(t, "Firewall", table.Name)
+	_, found = ini.Table("78")
+	assert.False(t, found)
+	_, found = ini.Table("")
+	assert.False(t, found)
+	var none *DeviceINI
+	_, found = none.Table("77")
+	assert.False(t, found)
+}
+
+func TestDeviceINI_Vendor(t *testing.T) {
+	for _, test := range []struct {
+		displayName string
+		expected    string
+	}{
+		{displayName: "Cisco PIX Firewall", expected: "Cisco"},
+		{displayName: " Juniper  SRX ", expected: "Juniper"},
+		{displayName: "Squid"},
+		{},
+	} {
+		ini := &DeviceINI{DisplayName: test.displayName}
+		assert.Equal(t, test.expected, ini.Vendor(), test.displayName)
+	}
+	var none *DeviceINI
+	assert.Empty(t, none.Vendor())
+}
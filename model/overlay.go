@@ -0,0 +1,122 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package model
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/adriansr/nwdevice2filebeat/util"
+)
+
+// CustomXMLSuffix is the suffix used by XML files that extend or override
+// the definitions in a device's base XML.
+const CustomXMLSuffix = "-custom.xml"
+
+// IsCustomXML returns if the given path is a custom XML overlay.
+func IsCustomXML(path string) bool {
+	return strings.HasSuffix(strings.ToLower(path), CustomXMLSuffix)
+}
+
+// Merge applies the definitions of a custom XML on top of the device.
+//
+// The rules are:
+//   - HEADER and MESSAGE elements are identified by their id1 attribute.
+//   - VALUEMAP, TAGVALMAP, REGX and VARTYPE elements are identified by their
+//     name.
+//   - An element with the same identifier as an existing one replaces it,
+//     keeping the original position. This includes elements defined earlier
+//     in the same overlay.
+//   - New HEADERs are inserted before the existing ones, so that custom
+//     headers take precedence. Any other new elements are appended.
+//   - SUMDATA elements are appended.
+//   - The DEVICEMESSAGES and VERSION elements of the overlay are ignored.
+//
+// A warning is emitted for every element that is overridden.
+func (dev *Device) Merge(custom Device, warnings *util.Warnings) {
+	mergeKeyed(&dev.Headers, custom.Headers, mergeKind{
+		name:    "HEADER",
+		key:     func(elem XMLElement) string { return elem.(*Header).ID1 },
+		prepend: true,
+	}, warnings)
+	mergeKeyed(&dev.Messages, custom.Messages, mergeKind{
+		name: "MESSAGE",
+		key:  func(elem XMLElement) string { return elem.(*Message).ID1 },
+	}, warnings)
+	mergeKeyed(&dev.ValueMaps, custom.ValueMaps, mergeKind{
+		name: "VALUEMAP",
+		key:  func(elem XMLElement) string { return elem.(*ValueMap).Name },
+	}, warnings)
+	mergeKeyed(&dev.Regexs, custom.Regexs, mergeKind{
+		name: "REGX",
+		key:  func(elem XMLElement) string { return elem.(*RegX).Name },
+	}, warnings)
+	mergeKeyed(&dev.VarTypes, custom.VarTypes, mergeKind{
+		name: "VARTYPE",
+		key:  func(elem XMLElement) string { return elem.(*VarType).Name },
+	}, warnings)
+	mergeKeyed(&dev.TagValMaps, custom.TagValMaps, mergeKind{
+		name: "TAGVALMAP",
+		key:  func(elem XMLElement) string { return elem.(*TagValMap).Name },
+	}, warnings)
+	dev.SumDatas = append(dev.SumDatas, custom.SumDatas...)
+}
+
+// mergeKind describes how the elements of a kind are merged.
+type mergeKind struct {
+	// name of the XML element.
+	name string
+	// key returns the identifier of an element.
+	key func(XMLElement) string
+	// prepend inserts new elements before the existing ones.
+	prepend bool
+}
+
+// mergeKeyed merges the elements in overlay into the slice pointed by list.
+// Both must be slices of the same XMLElement type. An element replaces the
+// one with the same key, keeping its position, be it from the list or added
+// earlier from the overlay.
+func mergeKeyed(list, overlay interface{}, kind mergeKind, warnings *util.Warnings) {
+	dst := reflect.ValueOf(list).Elem()
+	src := reflect.ValueOf(overlay)
+	index := make(map[string]int, dst.Len())
+	for idx := 0; idx < dst.Len(); idx++ {
+		index[kind.key(dst.Index(idx).Interface().(XMLElement))] = idx
+	}
+	added := reflect.MakeSlice(dst.Type(), 0, src.Len())
+	addedIndex := make(map[string]int, src.Len())
+	for idx := 0; idx < src.Len(); idx++ {
+		elem := src.Index(idx).Interface().(XMLElement)
+		key := kind.key(elem)
+		target := dst
+		pos, found := index[key]
+		if !found {
+			target = added
+			pos, found = addedIndex[key]
+		}
+		if found {
+			warnOverride(warnings, elem, target.Index(pos).Interface().(XMLElement), kind.name, key)
+			target.Index(pos).Set(src.Index(idx))
+			continue
+		}
+		addedIndex[key] = added.Len()
+		added = reflect.Append(added, src.Index(idx))
+	}
+	if added.Len() == 0 {
+		return
+	}
+	if kind.prepend {
+		dst.Set(reflect.AppendSlice(added, dst))
+	} else {
+		dst.Set(reflect.AppendSlice(dst, added))
+	}
+}
+
+func warnOverride(warnings *util.Warnings, elem, old XMLElement, kind, id string) {
+	if id != "" {
+		kind += " " + id
+	}
+	warnings.Addf(elem.Pos(), "%s overrides definition at %s", kind, old.Pos())
+}
@@ -0,0 +1,195 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adriansr/nwdevice2filebeat/util"
+)
+
+func TestIsCustomXML(t *testing.T) {
+	assert.True(t, IsCustomXML("devices/squid/squid-custom.xml"))
+	assert.True(t, IsCustomXML("devices/squid/SQUID-CUSTOM.XML"))
+	assert.False(t, IsCustomXML("devices/squid/v20_squidmsg.xml"))
+	assert.False(t, IsCustomXML("devices/squid/custom.xml"))
+}
+
+func TestDevice_Merge(t *testing.T) {
+	base := func(line uint64) util.XMLPos {
+		return util.XMLPos{Path: "base.xml", Line: line, Col: 1}
+	}
+	custom := func(line uint64) util.XMLPos {
+		return util.XMLPos{Path: "base-custom.xml", Line: line, Col: 1}
+	}
+	header := func(pos util.XMLPos, id1, content string) *Header {
+		h := &Header{ID1: id1, Content: content}
+		h.SetPos(pos)
+		return h
+	}
+	message := func(pos util.XMLPos, id1, id2 string) *Message {
+		m := &Message{ID1: id1, ID2: id2}
+		m.SetPos(pos)
+		return m
+	}
+	valueMap := func(pos util.XMLPos, name, kv string) *ValueMap {
+		vm := &ValueMap{Name: name, KeyValuePairs: kv}
+		vm.SetPos(pos)
+		return vm
+	}
+	tagValMap := func(pos util.XMLPos, delimiter string) *TagValMap {
+		tvm := &TagValMap{Delimiter: delimiter}
+		tvm.SetPos(pos)
+		return tvm
+	}
+	namedTagValMap := func(pos util.XMLPos, name, delimiter string) *TagValMap {
+		tvm := tagValMap(pos, delimiter)
+		tvm.Name = name
+		return tvm
+	}
+
+	for _, testCase := range []struct {
+		title    string
+		base     Device
+		custom   Device
+		expected Device
+		warnings []util.Warning
+	}{
+		{
+			title: "new elements",
+			base: Device{
+				Headers:   []*Header{header(base(1), "h1", "a")},
+				Messages:  []*Message{message(base(2), "m1", "id")},
+				ValueMaps: []*ValueMap{valueMap(base(3), "vm1", "a=b")},
+			},
+			custom: Device{
+				Headers:   []*Header{header(custom(1), "h2", "b")},
+				Messages:  []*Message{message(custom(2), "m2", "id")},
+				ValueMaps: []*ValueMap{valueMap(custom(3), "vm2", "c=d")},
+			},
+			expected: Device{
+				Headers: []*Header{
+					header(custom(1), "h2", "b"),
+					header(base(1), "h1", "a"),
+				},
+				Messages: []*Message{
+					message(base(2), "m1", "id"),
+					message(custom(2), "m2", "id"),
+				},
+				ValueMaps: []*ValueMap{
+					valueMap(base(3), "vm1", "a=b"),
+					valueMap(custom(3), "vm2", "c=d"),
+				},
+			},
+		},
+		{
+			title: "overrides",
+			base: Device{
+				Headers: []*Header{
+					header(base(1), "h1", "a"),
+					header(base(2), "h2", "b"),
+				},
+				Messages: []*Message{
+					message(base(3), "m1", "id1"),
+					message(base(4), "m2", "id2"),
+				},
+				ValueMaps:  []*ValueMap{valueMap(base(5), "vm1", "a=b")},
+				TagValMaps: []*TagValMap{tagValMap(base(6), "|")},
+			},
+			custom: Device{
+				Headers:    []*Header{header(custom(1), "h1", "c")},
+				Messages:   []*Message{message(custom(2), "m1", "other")},
+				ValueMaps:  []*ValueMap{valueMap(custom(3), "vm1", "e=f")},
+				TagValMaps: []*TagValMap{tagValMap(custom(4), ";")},
+			},
+			expected: Device{
+				Headers: []*Header{
+					header(custom(1), "h1", "c"),
+					header(base(2), "h2", "b"),
+				},
+				Messages: []*Message{
+					message(custom(2), "m1", "other"),
+					message(base(4), "m2", "id2"),
+				},
+				ValueMaps:  []*ValueMap{valueMap(custom(3), "vm1", "e=f")},
+				TagValMaps: []*TagValMap{tagValMap(custom(4), ";")},
+			},
+			warnings: []util.Warning{
+				{Pos: custom(1), Text: "HEADER h1 overrides definition at base.xml:1:1"},
+				{Pos: custom(2), Text: "MESSAGE m1 overrides definition at base.xml:3:1"},
+				{Pos: custom(3), Text: "VALUEMAP vm1 overrides definition at base.xml:5:1"},
+				{Pos: custom(4), Text: "TAGVALMAP overrides definition at base.xml:6:1"},
+			},
+		},
+		{
+			title: "named TAGVALMAPs",
+			base: Device{
+				TagValMaps: []*TagValMap{
+					tagValMap(base(1), "|"),
+					namedTagValMap(base(2), "csv", ","),
+				},
+			},
+			custom: Device{
+				TagValMaps: []*TagValMap{
+					namedTagValMap(custom(1), "csv", ";"),
+					namedTagValMap(custom(2), "space", " "),
+				},
+			},
+			expected: Device{
+				TagValMaps: []*TagValMap{
+					tagValMap(base(1), "|"),
+					namedTagValMap(custom(1), "csv", ";"),
+					namedTagValMap(custom(2), "space", " "),
+				},
+			},
+			warnings: []util.Warning{
+				{Pos: custom(1), Text: "TAGVALMAP csv overrides definition at base.xml:2:1"},
+			},
+		},
+		{
+			title: "duplicates in overlay",
+			base: Device{
+				Headers:  []*Header{header(base(1), "h1", "a")},
+				Messages: []*Message{message(base(2), "m1", "id")},
+			},
+			custom: Device{
+				Headers: []*Header{
+					header(custom(1), "h2", "b"),
+					header(custom(2), "h3", "c"),
+					header(custom(3), "h2", "d"),
+				},
+				Messages: []*Message{
+					message(custom(4), "m2", "id"),
+					message(custom(5), "m2", "other"),
+				},
+			},
+			expected: Device{
+				Headers: []*Header{
+					header(custom(3), "h2", "d"),
+					header(custom(2), "h3", "c"),
+					header(base(1), "h1", "a"),
+				},
+				Messages: []*Message{
+					message(base(2), "m1", "id"),
+					message(custom(5), "m2", "other"),
+				},
+			},
+			warnings: []util.Warning{
+				{Pos: custom(3), Text: "HEADER h2 overrides definition at base-custom.xml:1:1"},
+				{Pos: custom(5), Text: "MESSAGE m2 overrides definition at base-custom.xml:4:1"},
+			},
+		},
+	} {
+		t.Run(testCase.title, func(t *testing.T) {
+			warnings := util.NewWarnings(10)
+			dev := testCase.base
+			dev.Merge(testCase.custom, &warnings)
+			assert.Equal(t, testCase.expected, dev)
+			assert.Equal(t, testCase.warnings, warnings.Message)
+		})
+	}
+}
@@ -0,0 +1,97 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package model
+
+import (
+	"bufio"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// WriteXML serializes the device as a normalized NetWitness XML.
+//
+// The output is stable: Elements are grouped by type in a fixed order,
+// keeping the original order within each type, and attributes are written
+// one per line in the order they're declared in the model. Empty attributes
+// are omitted, as the loader doesn't distinguish them from missing ones.
+func (dev *Device) WriteXML(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	writeElement(bw, "DEVICEMESSAGES", &dev.Description, false)
+	if len(elementAttrs(&dev.Version)) > 0 {
+		writeElement(bw, "VERSION", &dev.Version, true)
+	}
+	for _, elem := range dev.VarTypes {
+		writeElement(bw, "VARTYPE", elem, true)
+	}
+	for _, elem := range dev.Regexs {
+		writeElement(bw, "REGX", elem, true)
+	}
+	for _, elem := range dev.ValueMaps {
+		writeElement(bw, "VALUEMAP", elem, true)
+	}
+	for _, elem := range dev.TagValMaps {
+		writeElement(bw, "TAGVALMAP", elem, true)
+	}
+	for _, elem := range dev.Headers {
+		writeElement(bw, "HEADER", elem, true)
+	}
+	for _, elem := range dev.Messages {
+		writeElement(bw, "MESSAGE", elem, true)
+	}
+	for _, elem := range dev.SumDatas {
+		writeElement(bw, "SUMDATA", elem, true)
+	}
+	bw.WriteString("</DEVICEMESSAGES>\n")
+	return errors.Wrap(bw.Flush(), "error writing XML")
+}
+
+func writeElement(w *bufio.Writer, name string, elem interface{}, selfClosing bool) {
+	w.WriteByte('<')
+	w.WriteString(name)
+	for _, attr := range elementAttrs(elem) {
+		w.WriteString("\n\t")
+		w.WriteString(attr[0])
+		w.WriteString("=\"")
+		w.WriteString(attrEscaper.Replace(attr[1]))
+		w.WriteByte('"')
+	}
+	if selfClosing {
+		w.WriteString(" />\n\n")
+	} else {
+		w.WriteString(">\n\n")
+	}
+}
+
+var attrEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	"\"", "&quot;",
+	"\t", "&#x9;",
+	"\n", "&#xA;",
+	"\r", "&#xD;",
+)
+
+// elementAttrs returns the non-empty attributes of an element as (name, value)
+// pairs, using the names in the `xml` struct tags.
+func elementAttrs(elem interface{}) (attrs [][2]string) {
+	v := reflect.Indirect(reflect.ValueOf(elem))
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("xml"), ",")
+		if len(tag) != 2 || tag[1] != "attr" || tag[0] == "" || field.Type.Kind() != reflect.String {
+			continue
+		}
+		if value := v.Field(i).String(); value != "" {
+			attrs = append(attrs, [2]string{tag[0], value})
+		}
+	}
+	return attrs
+}
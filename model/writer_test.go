@@ -0,0 +1,154 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package model
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adriansr/nwdevice2filebeat/util"
+)
+
+func roundTrip(t *testing.T, dev Device) (Device, []byte) {
+	var buf bytes.Buffer
+	if !assert.NoError(t, dev.WriteXML(&buf)) {
+		t.FailNow()
+	}
+	dir, err := ioutil.TempDir("", "nwdevice_fmt")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "device.xml")
+	if !assert.NoError(t, ioutil.WriteFile(path, buf.Bytes(), 0644)) {
+		t.FailNow()
+	}
+	result, err := NewDeviceFromXML(path)
+	if !assert.NoError(t, err, buf.String()) {
+		t.FailNow()
+	}
+	return result, buf.Bytes()
+}
+
+// clearPositions removes the parts of a device that depend on the source file.
+func clearPositions(dev *Device) {
+	var none util.XMLPos
+	dev.XMLPath = ""
+	dev.CustomXMLPaths = nil
+	dev.INI = nil
+	dev.Description.SetPos(none)
+	dev.Version.SetPos(none)
+	for _, list := range [][]XMLElement{
+		toElements(dev.Headers), toElements(dev.Messages), toElements(dev.TagValMaps),
+		toElements(dev.ValueMaps), toElements(dev.VarTypes), toElements(dev.Regexs),
+		toElements(dev.SumDatas),
+	} {
+		for _, elem := range list {
+			elem.SetPos(none)
+		}
+	}
+}
+
+func toElements(list interface{}) (result []XMLElement) {
+	switch v := list.(type) {
+	case []*Header:
+		for _, e := range v {
+			result = append(result, e)
+		}
+	case []*Message:
+		for _, e := range v {
+			result = append(result, e)
+		}
+	case []*TagValMap:
+		for _, e := range v {
+			result = append(result, e)
+		}
+	case []*ValueMap:
+		for _, e := range v {
+			result = append(result, e)
+		}
+	case []*VarType:
+		for _, e := range v {
+			result = append(result, e)
+		}
+	case []*RegX:
+		for _, e := range v {
+			result = append(result, e)
+		}
+	case []*SumData:
+		for _, e := range v {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+func TestDevice_WriteXML(t *testing.T) {
+	dev := Device{
+		Description: DeviceHeader{Name: "test", DisplayName: "Test & Co.", Group: "Unix"},
+		Version:     Version{XML: "1", Revision: "2", Device: "2.0"},
+		Headers: []*Header{
+			{ID1: "0001", ID2: "0001", Content: "<hfld1> \"<messageid>\" <!payload>"},
+		},
+		Messages: []*Message{
+			{ID1: "M1", ID2: "M", EventCategory: "1", Functions: "<@msg:*PARMVAL($MSG)>", Content: "a\tb\nc <fld1> 'quoted'"},
+		},
+		ValueMaps: []*ValueMap{
+			{Name: "map", Default: "$NONE", KeyValuePairs: "'1'='one'|'2'='two'"},
+		},
+		TagValMaps: []*TagValMap{
+			{Delimiter: "|", PairDelimiter: "="},
+		},
+		VarTypes: []*VarType{
+			{Name: "num", Regex: "[0-9]+"},
+		},
+		Regexs: []*RegX{
+			{Name: "rx", Parms: "$IN", Expr: "<$IN>(a|b) ? 'x'", Default: "$NONE"},
+		},
+		SumDatas: []*SumData{
+			{Bucket: "b", Key: "k", Fields: "a,b"},
+		},
+	}
+	result, data := roundTrip(t, dev)
+	clearPositions(&result)
+	assert.Equal(t, dev, result)
+	assert.Contains(t, string(data), `displayname="Test &amp; Co."`)
+	assert.Contains(t, string(data), `content="a&#x9;b&#xA;c &lt;fld1&gt; 'quoted'"`)
+
+	// Writing the result again must produce the same output.
+	_, again := roundTrip(t, result)
+	assert.Equal(t, string(data), string(again))
+}
+
+func TestDevice_WriteXML_Devices(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping round-trip of all devices in short mode")
+	}
+	dirs, err := filepath.Glob(filepath.Join("..", "devices", "*"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, dir := range dirs {
+		if isDir, _ := util.IsDir(dir); !isDir {
+			continue
+		}
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			dev, err := NewDevice(dir, nil)
+			if err != nil {
+				// Round-trip only applies to devices that can be loaded.
+				t.Skipf("device doesn't load: %v", err)
+			}
+			result, _ := roundTrip(t, dev)
+			clearPositions(&dev)
+			clearPositions(&result)
+			assert.Equal(t, dev, result)
+		})
+	}
+}
@@ -50,6 +50,7 @@ func (js *javascript) Populate(lyt *layout.Generator) (err error) {
       ecs: true
       rsa: {{((getvar "var_prefix"))rsa_fields}}
       tz_offset: {{((getvar "var_prefix"))tz_offset}}
+      local_networks: "{{((getvar "var_prefix"))local_networks}}"
       keep_raw: {{((getvar "var_prefix"))keep_raw_fields}}
       debug: {{((getvar "var_prefix"))debug}}`
 	if lyt.HasDir("config.dir") {
@@ -162,6 +163,20 @@ func generate(op parser.Operation, out *output.CodeWriter) {
 		}
 		out.Unindent().Write("};").Newline()
 
+	case parser.Regex:
+		out.Newline()
+		out.Writef("var regx_%s = {", v.Name).Newline().
+			Indent().
+			Write("expr: new RegExp(").JS(v.Expr).Write(", \"g\"),").Newline().
+			Write("input: ").JS(v.Input).Write(",").Newline().
+			Write("replacement: ").JS(v.Replacement).Write(",").Newline()
+		if v.Default != nil {
+			out.JS("default").Write(": ")
+			generate(*v.Default, out)
+			out.Write(",").Newline()
+		}
+		out.Unindent().Write("};").Newline()
+
 	case parser.Constant:
 		out.Write("constant(").JS(v.Value()).Write(")")
 
@@ -187,6 +202,10 @@ func generate(op parser.Operation, out *output.CodeWriter) {
 	case parser.MsgIdSelect:
 		out.Write("msgid_select(")
 		writeMapping(v.Map, v.Nodes, out)
+		if v.Default != nil {
+			out.Write(", ")
+			generate(v.Default, out)
+		}
 		out.Write(")")
 
 	case parser.Match:
@@ -199,7 +218,13 @@ func generate(op parser.Operation, out *output.CodeWriter) {
 			sort.Slice(keyValues, func(i, j int) bool {
 				return keyValues[i][0] < keyValues[j][0]
 			})
-			out.Write("tagval(").JS(v.ID).Write(", ").JS(v.Input).Write(", tvm, {").Newline()
+			out.Write("tagval(").JS(v.ID).Write(", ").JS(v.Input).Write(", ")
+			if v.TagValues.MapName != "" {
+				out.Write("tvms[").JS(v.TagValues.MapName).Write("]")
+			} else {
+				out.Write("tvm")
+			}
+			out.Write(", {").Newline()
 			for _, entry := range keyValues {
 				out.Indent().JS(entry[0]).Write(": ").JS(entry[1]).Write(",").Unindent().Newline()
 			}
@@ -294,6 +319,17 @@ func generate(op parser.Operation, out *output.CodeWriter) {
 		out.Write(",").Newline().Unindent()
 		out.Write("})")
 
+	case parser.RegexCall:
+		out.Write("regx({").Newline().Indent().
+			Write("dest: ").JS(v.Target).Write(",").Newline().
+			Write("regx: ").Write("regx_" + v.Name).Write(",").Newline().
+			Write("args: [").Newline().Indent()
+		for _, arg := range v.Args {
+			generate(arg, out)
+			out.Write(",").Newline()
+		}
+		out.Unindent().Write("],").Unindent().Newline().Write("})")
+
 	case parser.DateTime:
 		writeDateTimeLike(v, "date_time", "d", out)
 
@@ -326,15 +362,67 @@ func generate(op parser.Operation, out *output.CodeWriter) {
 		out.Write(")")
 
 	case TagValMapCfg:
-		if v.TagValMapSettings == nil {
+		if v.TagValMapSettings != nil {
+			out.Write("var tvm = ")
+			writeTagValMap(v.TagValMapSettings, out)
+			out.Write(";").Newline()
+		}
+		if len(v.Named) == 0 {
+			return
+		}
+		names := make([]string, 0, len(v.Named))
+		for name := range v.Named {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		out.Write("var tvms = {").Newline().Indent()
+		for _, name := range names {
+			out.JS(name).Write(": ")
+			writeTagValMap(v.Named[name], out)
+			out.Write(",").Newline()
+		}
+		out.Unindent().Write("};").Newline()
+
+	case VarTypesCfg:
+		if len(v) == 0 {
+			return
+		}
+		out.Write("var vartypes = {").Newline().Indent()
+		for _, vt := range v {
+			flags := ""
+			if vt.IgnoreCase {
+				flags = "i"
+			}
+			out.JS(vt.Name).Write(": new RegExp(").JS(vt.Pattern()).Write(", ").JS(flags).Write("),").Newline()
+		}
+		out.Unindent().Write("};").Newline()
+
+	case EventCategoriesCfg:
+		if len(v) == 0 {
 			return
 		}
-		out.Write("var tvm = {").Newline().Indent().
-			Write("pair_separator: ").JS(v.PairSeparator).Write(",").Newline().
-			Write("kv_separator: ").JS(v.KeyValueSeparator).Write(",").Newline().
-			Write("open_quote: ").JS(v.OpenQuote).Write(",").Newline().
-			Write("close_quote: ").JS(v.CloseQuote).Write(",").Newline().
-			Unindent().Write("};").Newline()
+		out.Write("var event_categories = {").Newline().Indent()
+		for _, cat := range v {
+			out.JS(cat.Code).Write(": {")
+			sep := ""
+			for _, field := range []struct {
+				name  string
+				value interface{}
+				empty bool
+			}{
+				{"kind", cat.Kind, cat.Kind == ""},
+				{"category", cat.Category, len(cat.Category) == 0},
+				{"type", cat.Type, len(cat.Type) == 0},
+				{"outcome", cat.Outcome, cat.Outcome == ""},
+			} {
+				if !field.empty {
+					out.Write(sep).Write(field.name).Write(": ").JS(field.value)
+					sep = ", "
+				}
+			}
+			out.Write("},").Newline()
+		}
+		out.Unindent().Write("};").Newline()
 
 	default:
 		out.Writef("/* TODO: here goes a %T */", v)
@@ -361,6 +449,16 @@ func writeMapping(m map[string]int, nodes []parser.Operation, out *output.CodeWr
 	out.Unindent().Write("}")
 }
 
+func writeTagValMap(tvm *parser.TagValMapSettings, out *output.CodeWriter) {
+	out.Write("{").Newline().Indent().
+		Write("pair_separator: ").JS(tvm.PairSeparator).Write(",").Newline().
+		Write("kv_separator: ").JS(tvm.KeyValueSeparator).Write(",").Newline().
+		Write("open_quote: ").JS(tvm.OpenQuote).Write(",").Newline().
+		Write("close_quote: ").JS(tvm.CloseQuote).Write(",").Newline().
+		Write("escape: ").JS(tvm.KeyValueEscape).Write(",").Newline().
+		Unindent().Write("}")
+}
+
 func writeMapString(m map[string]string, out *output.CodeWriter) {
 	out.Write("{").Newline().Indent()
 	keys := make([]string, len(m))
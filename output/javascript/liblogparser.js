@@ -17,10 +17,24 @@ var defaults = {
     rsa: false,
     keep_raw: false,
     tz_offset: "local",
-    strip_priority: true
+    strip_priority: true,
+    // Networks considered local by DIRCHK.
+    local_networks: [
+        "10.0.0.0/8",
+        "172.16.0.0/12",
+        "192.168.0.0/16",
+        "127.0.0.0/8",
+        "fc00::/7",
+        "::1/128",
+    ]
 };
 
 var saved_flags = null;
+// Overridden by the device's pipeline when it defines VARTYPEs.
+var vartypes = {};
+// Overridden by the device's pipeline with the ECS categorization for the
+// MESSAGE eventcategory codes it uses.
+var event_categories = {};
 var debug;
 var map_ecs;
 var map_rsa;
@@ -28,6 +42,7 @@ var keep_raw;
 var device;
 var tz_offset;
 var strip_priority;
+var local_networks;
 
 // Register params from configuration.
 function register(params) {
@@ -37,6 +52,7 @@ function register(params) {
     keep_raw = params.keep_raw !== undefined ? params.keep_raw : defaults.keep_raw;
     tz_offset = parse_tz_offset(params.tz_offset !== undefined? params.tz_offset : defaults.tz_offset);
     strip_priority = params.strip_priority !== undefined? params.strip_priority : defaults.strip_priority;
+    local_networks = parse_local_networks(params.local_networks ? params.local_networks : defaults.local_networks);
     device = new DeviceProcessor();
 }
 
@@ -168,10 +184,20 @@ function match(id, src, pattern, on_success) {
         overwrite_keys: true,
         trim_values: "right"
     });
+    var checks = vartype_checks(pattern);
+    var keys = checks.length > 0 ? pattern_keys(pattern) : [];
     return function (evt) {
         var msg = evt.Get(src);
+        var saved = save_fields(evt, keys);
         dissect.Run(evt);
         var failed = evt.Get(FLAG_FIELD) != null;
+        if (!failed && !check_vartypes(evt, checks)) {
+            // Undo the captures so they don't leak into the next pattern
+            // tried or the final event.
+            restore_fields(evt, saved);
+            evt.Put(FLAG_FIELD, "vartype_validation_error");
+            failed = true;
+        }
         if (debug) {
             if (failed) {
                 console.debug("dissect fail: " + id + " field:" + src);
@@ -187,6 +213,70 @@ function match(id, src, pattern, on_success) {
     };
 }
 
+// pattern_keys returns the fields where the given dissect pattern stores
+// its captures. Skipped (%{?key}) and unnamed keys are not included.
+function pattern_keys(pattern) {
+    var keys = [];
+    var re = /%\{([^}]*)\}/g;
+    var m;
+    while ((m = re.exec(pattern)) !== null) {
+        if (m[1].charAt(0) === "?") continue;
+        var key = m[1].replace(/^[+*&]/, "").replace(/->$/, "").replace(/\/\d+$/, "");
+        if (key !== "" && keys.indexOf(FIELDS_PREFIX + key) === -1) {
+            keys.push(FIELDS_PREFIX + key);
+        }
+    }
+    return keys;
+}
+
+// save_fields returns the current values of the given fields, null for
+// fields that are not set.
+function save_fields(evt, fields) {
+    var saved = [];
+    for (var i = 0; i < fields.length; i++) {
+        saved.push({field: fields[i], value: evt.Get(fields[i])});
+    }
+    return saved;
+}
+
+// restore_fields reverts the fields to the values returned by save_fields.
+function restore_fields(evt, saved) {
+    for (var i = 0; i < saved.length; i++) {
+        if (saved[i].value == null) {
+            evt.Delete(saved[i].field);
+        } else {
+            evt.Put(saved[i].field, saved[i].value);
+        }
+    }
+}
+
+// vartype_checks returns the VARTYPE expressions that apply to the keys
+// captured by the given dissect pattern.
+function vartype_checks(pattern) {
+    var checks = [];
+    var re = /%\{([^}]*)\}/g;
+    var m;
+    while ((m = re.exec(pattern)) !== null) {
+        var key = m[1].replace(/^[?+*&]/, "").replace(/->$/, "").replace(/\/\d+$/, "");
+        var expr = vartypes[key];
+        if (expr !== undefined) {
+            checks.push({field: FIELDS_PREFIX + key, expr: expr});
+        }
+    }
+    return checks;
+}
+
+function check_vartypes(evt, checks) {
+    for (var i = 0; i < checks.length; i++) {
+        var value = evt.Get(checks[i].field);
+        if (value != null && !checks[i].expr.test(value)) {
+            if (debug) console.debug("vartype check failed for " + checks[i].field + ": <<" + value + ">>");
+            return false;
+        }
+    }
+    return true;
+}
+
 function match_copy(id, src, dst, on_success) {
     dst = FIELDS_PREFIX + dst;
     if (dst === FIELDS_PREFIX || dst === src) {
@@ -235,7 +325,9 @@ function all_match(opts) {
     };
 }
 
-function msgid_select(mapping) {
+// catch_all is optional. It's used for messageids without a mapping, and the
+// event is flagged as unknown_messageid.
+function msgid_select(mapping, catch_all) {
     return function (evt) {
         var msgid = evt.Get(FIELDS_PREFIX + "messageid");
         if (msgid == null) {
@@ -244,6 +336,14 @@ function msgid_select(mapping) {
         }
         var next = mapping[msgid];
         if (next === undefined) {
+            if (catch_all !== undefined) {
+                if (debug) console.warn("msgid_select: using catch-all for messageid:" + msgid);
+                catch_all(evt);
+                if (evt.Get(FLAG_FIELD) == null) {
+                    evt.Put(FLAG_FIELD, "unknown_messageid");
+                }
+                return;
+            }
             if (debug) console.warn("msgid_select: no mapping for messageid:" + msgid);
             return;
         }
@@ -297,9 +397,186 @@ function STRCAT(args) {
     return s;
 }
 
-// TODO: Implement
-function DIRCHK(args) {
-    unimplemented("DIRCHK");
+// DIRCHK(saddr) returns "1" (outbound) if saddr is local, "0" (inbound)
+// otherwise.
+// DIRCHK($IN|$OUT,saddr,daddr[,sport,dport]) returns "1" for traffic from a
+// local to a remote address and "0" for the opposite. When both or none of the
+// addresses are local, $IN returns "0" and $OUT "1". The ports are used to swap
+// source and destination when saddr is the server side of the connection.
+// Also sets the direction field (network.direction) unless already set.
+function DIRCHK(args, evt) {
+    var result, direction;
+    switch (args.length) {
+        case 1:
+            var local = is_local_address(args[0]);
+            if (local === undefined) return;
+            result = local? "1" : "0";
+            direction = local? "outbound" : "inbound";
+            break;
+        case 3:
+        case 5:
+            if (args[0] !== "$IN" && args[0] !== "$OUT") {
+                console.warn("DIRCHK: unknown default direction " + args[0]);
+                return;
+            }
+            var src = args[1], dst = args[2];
+            if (args.length === 5 && is_server_port(args[3]) && !is_server_port(args[4])) {
+                src = args[2];
+                dst = args[1];
+            }
+            var srcLocal = is_local_address(src);
+            var dstLocal = is_local_address(dst);
+            if (srcLocal === undefined || dstLocal === undefined) return;
+            if (srcLocal === dstLocal) {
+                result = args[0] === "$IN"? "0" : "1";
+                direction = srcLocal? "internal" : "external";
+            } else {
+                result = srcLocal? "1" : "0";
+                direction = srcLocal? "outbound" : "inbound";
+            }
+            break;
+        default:
+            console.warn("DIRCHK: unsupported number of arguments: " + args.length);
+            return;
+    }
+    if (evt !== undefined && evt.Get(FIELDS_PREFIX + "direction") == null) {
+        evt.Put(FIELDS_PREFIX + "direction", direction);
+    }
+    return result;
+}
+
+function is_server_port(port) {
+    var num = strictToInt(port);
+    return !isNaN(num) && num > 0 && num < 1024;
+}
+
+function is_local_address(addr) {
+    var ip = parse_ip(addr);
+    if (ip === undefined) {
+        if (debug) console.warn("DIRCHK: failed to parse IP address: " + addr);
+        return;
+    }
+    ip = unmap_ipv4(ip);
+    for (var i = 0; i < local_networks.length; i++) {
+        if (network_contains(local_networks[i], ip)) return true;
+    }
+    return false;
+}
+
+// Accepts a list of networks in CIDR notation or a comma-separated string.
+function parse_local_networks(list) {
+    if (typeof list === "string") list = list.split(",");
+    var result = [];
+    for (var i = 0; i < list.length; i++) {
+        var str = list[i].trim();
+        if (str === "") continue;
+        var network = parse_cidr(str);
+        if (network === undefined) throw "invalid local network: '" + str + "'";
+        result.push(network);
+    }
+    return result;
+}
+
+function parse_cidr(str) {
+    var pos = str.indexOf("/");
+    var ip = parse_ip(pos === -1? str : str.substr(0, pos));
+    if (ip === undefined) return;
+    var bits = ip.length * 8;
+    if (pos !== -1) {
+        bits = strictToInt(str.substr(pos + 1));
+        if (isNaN(bits) || bits < 0 || bits > ip.length * 8) return;
+    }
+    if (ip.length === 16) {
+        // A network of IPv4-mapped addresses is an IPv4 network.
+        var masked = ip.slice();
+        for (var i = 0; i < 16; i++) {
+            var n = Math.min(Math.max(bits - 8 * i, 0), 8);
+            masked[i] &= (0xff << (8 - n)) & 0xff;
+        }
+        if (is_ipv4_mapped(masked)) {
+            return {ip: masked.slice(12), bits: Math.max(bits - 96, 0)};
+        }
+    }
+    return {ip: ip, bits: bits};
+}
+
+// IPv4-mapped IPv6 addresses (::ffff:a.b.c.d) are handled as IPv4 addresses,
+// as Go's net package does.
+function is_ipv4_mapped(ip) {
+    if (ip.length !== 16 || ip[10] !== 0xff || ip[11] !== 0xff) return false;
+    for (var i = 0; i < 10; i++) {
+        if (ip[i] !== 0) return false;
+    }
+    return true;
+}
+
+function unmap_ipv4(ip) {
+    return is_ipv4_mapped(ip)? ip.slice(12) : ip;
+}
+
+function network_contains(network, ip) {
+    if (network.ip.length !== ip.length) return false;
+    var bits = network.bits;
+    for (var i = 0; bits > 0; i++, bits -= 8) {
+        var mask = bits >= 8? 0xff : (0xff << (8 - bits)) & 0xff;
+        if ((network.ip[i] & mask) !== (ip[i] & mask)) return false;
+    }
+    return true;
+}
+
+// Parses an IPv4 or IPv6 address into an array of bytes.
+function parse_ip(str) {
+    if (typeof str !== "string") return;
+    str = str.trim();
+    return str.indexOf(":") === -1? parse_ipv4(str) : parse_ipv6(str);
+}
+
+function parse_ipv4(str) {
+    var parts = str.split(".");
+    if (parts.length !== 4) return;
+    var ip = new Array(4);
+    for (var i = 0; i < 4; i++) {
+        if (!/^[0-9]{1,3}$/.test(parts[i])) return;
+        ip[i] = parseInt(parts[i], 10);
+        if (ip[i] > 255) return;
+    }
+    return ip;
+}
+
+function parse_ipv6(str) {
+    var halves = str.split("::");
+    if (halves.length > 2) return;
+    var head = parse_ipv6_groups(halves[0]);
+    var tail = halves.length === 2? parse_ipv6_groups(halves[1]) : [];
+    if (head === undefined || tail === undefined) return;
+    var missing = 8 - head.length - tail.length;
+    if (halves.length === 2? missing < 1 : missing !== 0) return;
+    var groups = head;
+    for (; missing > 0; missing--) groups.push(0);
+    groups = groups.concat(tail);
+    var ip = new Array(16);
+    for (var i = 0; i < 8; i++) {
+        ip[2*i] = groups[i] >> 8;
+        ip[2*i+1] = groups[i] & 0xff;
+    }
+    return ip;
+}
+
+function parse_ipv6_groups(str) {
+    if (str === "") return [];
+    var parts = str.split(":");
+    var groups = [];
+    for (var i = 0; i < parts.length; i++) {
+        if (i === parts.length - 1 && parts[i].indexOf(".") !== -1) {
+            var v4 = parse_ipv4(parts[i]);
+            if (v4 === undefined) return;
+            groups.push(v4[0] << 8 | v4[1], v4[2] << 8 | v4[3]);
+            continue;
+        }
+        if (!/^[0-9a-fA-F]{1,4}$/.test(parts[i])) return;
+        groups.push(parseInt(parts[i], 16));
+    }
+    return groups;
 }
 
 function strictToInt(str) {
@@ -353,12 +630,54 @@ function RMQ(args) {
             : value;
 }
 
+// CNVTDOMAIN converts a domain name in DNS wire-format, where each label is
+// preceded by its length, to dotted form:
+// (3)www(7)example(3)com(0) => www.example.com
+// Values that are not in wire-format are returned unchanged.
+function CNVTDOMAIN(args) {
+    if(args.length !== 1) {
+        console.warn("CNVTDOMAIN: only one argument expected");
+        return;
+    }
+    var str = args[0].trim();
+    if (str.charAt(0) !== "(") return str;
+    var labels = [];
+    for (var pos = 0; pos < str.length;) {
+        var end = str.indexOf(")", pos);
+        if (str.charAt(pos) !== "(" || end === -1) {
+            if (debug) console.warn("CNVTDOMAIN: invalid label length at offset " + pos + " in '" + str + "'");
+            return;
+        }
+        var len = str.substring(pos + 1, end);
+        var n = /^[0-9]+$/.test(len)? parseInt(len, 10) : NaN;
+        if (isNaN(n)) {
+            if (debug) console.warn("CNVTDOMAIN: invalid label length at offset " + pos + " in '" + str + "'");
+            return;
+        }
+        pos = end + 1;
+        if (n === 0) {
+            if (pos !== str.length) {
+                if (debug) console.warn("CNVTDOMAIN: unexpected data after domain terminator in '" + str + "'");
+                return;
+            }
+            break;
+        }
+        if (pos + n > str.length) {
+            if (debug) console.warn("CNVTDOMAIN: label at offset " + pos + " exceeds input length in '" + str + "'");
+            return;
+        }
+        labels.push(str.substr(pos, n));
+        pos += n;
+    }
+    return labels.join(".");
+}
+
 function call(opts) {
     var args = new Array(opts.args.length);
     return function (evt) {
         for (var i = 0; i < opts.args.length; i++)
             if ((args[i] = opts.args[i](evt)) == null) return;
-        var result = opts.fn(args);
+        var result = opts.fn(args, evt);
         if (result != null) {
             evt.Put(opts.dest, result);
         }
@@ -398,6 +717,27 @@ function lookup(opts) {
     };
 }
 
+function regx(opts) {
+    var args = new Array(opts.args.length);
+    return function (evt) {
+        for (var i = 0; i < opts.args.length; i++)
+            if ((args[i] = opts.args[i](evt)) == null) args[i] = "";
+        var input = args[opts.regx.input];
+        var re = opts.regx.expr;
+        re.lastIndex = 0;
+        if (!re.test(input)) {
+            if (opts.regx.default !== undefined) {
+                evt.Put(opts.dest, opts.regx.default(evt));
+            }
+            return;
+        }
+        re.lastIndex = 0;
+        evt.Put(opts.dest, input.replace(re, function () {
+            return opts.regx.replacement;
+        }));
+    };
+}
+
 function set(fields) {
     return new processor.AddFields({
         target: FIELDS_OBJECT,
@@ -945,24 +1285,31 @@ function tagval(id, src, cfg, keys, on_success) {
     var quotes_len = cfg.open_quote.length > 0 && cfg.close_quote.length > 0?
         cfg.open_quote.length + cfg.close_quote.length : 0;
     var kv_regex = new RegExp('^([^' + cfg.kv_separator + ']*)*' + cfg.kv_separator + ' *(.*)*$');
+    var escape = cfg.escape !== undefined? cfg.escape : "";
+    var unescape = function(str) {
+        return escape !== ""? tagval_unescape(str, cfg) : str;
+    };
     return function(evt) {
         var msg = evt.Get(src);
         if (msg === undefined) {
             console.warn("tagval: input field is missing");
             return fail(evt);
         }
-        var pairs = msg.split(cfg.pair_separator);
+        var pairs = escape !== ""?
+            tagval_split(msg, cfg.pair_separator, cfg) : msg.split(cfg.pair_separator);
         var i;
         var success = false;
         var prev = "";
+        var captured = {};
         for (i=0; i<pairs.length; i++) {
-            var m = pairs[i].match(kv_regex);
+            var m = escape !== ""?
+                tagval_match_escaped(pairs[i], cfg) : pairs[i].match(kv_regex);
             var field;
             if (m === null || m.length !== 3 || m[1] === undefined || m[2] === undefined) {
                 prev += pairs[i] + cfg.pair_separator;
                 continue;
             }
-            var key = prev + m[1];
+            var key = unescape(prev + m[1]);
             prev = "";
             if ( (field=keys[key]) === undefined && (field=keys[key.trim()])===undefined ) {
                 continue;
@@ -974,18 +1321,96 @@ function tagval(id, src, cfg, keys, on_success) {
                 value.substr(value.length - cfg.close_quote.length) === cfg.close_quote) {
                 value = value.substr(cfg.open_quote.length, value.length - quotes_len);
             }
-            evt.Put(FIELDS_PREFIX + field, value);
+            captured[field] = unescape(value);
             success = true;
         }
         if (!success) {
             return fail(evt);
         }
+        // As in match(), a value that doesn't match its VARTYPE rejects the
+        // whole message and nothing is captured.
+        for (field in captured) {
+            var expr = vartypes[field];
+            if (expr !== undefined && !expr.test(captured[field])) {
+                if (debug) console.debug("vartype check failed for " + field + ": <<" + captured[field] + ">>");
+                evt.Put(FLAG_FIELD, "vartype_validation_error");
+                return;
+            }
+        }
+        for (field in captured) {
+            evt.Put(FIELDS_PREFIX + field, captured[field]);
+        }
         if (on_success != null) {
             on_success(evt);
         }
     }
 }
 
+// Returns the length of the escapable sequence (escape or separator) at the
+// given position of str, or zero if there's none.
+function tagval_escaped_len(str, pos, cfg) {
+    var seqs = [cfg.escape, cfg.pair_separator, cfg.kv_separator];
+    for (var i = 0; i < seqs.length; i++) {
+        if (seqs[i] !== "" && str.substr(pos, seqs[i].length) === seqs[i]) {
+            return seqs[i].length;
+        }
+    }
+    return 0;
+}
+
+// Returns the position of the first occurrence of sep in str that is not
+// escaped, or -1.
+function tagval_index(str, sep, cfg) {
+    for (var pos = 0; pos < str.length; pos++) {
+        if (str.substr(pos, cfg.escape.length) === cfg.escape) {
+            pos += cfg.escape.length + tagval_escaped_len(str, pos + cfg.escape.length, cfg) - 1;
+            continue;
+        }
+        if (str.substr(pos, sep.length) === sep) return pos;
+    }
+    return -1;
+}
+
+// Splits str at the occurrences of sep that are not escaped. Escape sequences
+// are kept.
+function tagval_split(str, sep, cfg) {
+    var result = [];
+    var pos;
+    while ((pos = tagval_index(str, sep, cfg)) !== -1) {
+        result.push(str.substr(0, pos));
+        str = str.substr(pos + sep.length);
+    }
+    result.push(str);
+    return result;
+}
+
+// Equivalent to matching the tagval kv_regex, using the first key-value
+// separator that is not escaped.
+function tagval_match_escaped(str, cfg) {
+    var pos = tagval_index(str, cfg.kv_separator, cfg);
+    if (pos === -1) return null;
+    var key = str.substr(0, pos);
+    var value = str.substr(pos + cfg.kv_separator.length).replace(/^ */, "");
+    return [str, key !== ""? key : undefined, value !== ""? value : undefined];
+}
+
+function tagval_unescape(str, cfg) {
+    var result = "";
+    for (var pos = 0; pos < str.length; pos++) {
+        if (str.substr(pos, cfg.escape.length) === cfg.escape) {
+            var n = tagval_escaped_len(str, pos + cfg.escape.length, cfg);
+            if (n > 0) {
+                pos += cfg.escape.length;
+                result += str.substr(pos, n);
+                pos += n - 1;
+                continue;
+            }
+        }
+        result += str.charAt(pos);
+    }
+    return result;
+}
+
 var ecs_mappings = {
     "_facility": {convert: to_long, to:[{field: "log.syslog.facility.code", setter: fld_set}]},
     "_pri": {convert: to_long, to:[{field: "log.syslog.priority", setter: fld_set}]},
@@ -1054,6 +1479,7 @@ var ecs_mappings = {
     "ipv6.orig": {convert: to_ip, to:[{field: "network.forwarded_ip", setter: fld_prio, prio: 2},{field: "related.ip", setter: fld_append}]},
     "latdec_dst": {convert: to_double, to:[{field: "destination.geo.location.lat", setter: fld_set}]},
     "latdec_src": {convert: to_double, to:[{field: "source.geo.location.lat", setter: fld_set}]},
+    "level": {convert: to_long, to:[{field: "event.severity", setter: fld_set}]},
     "location_city": {to:[{field: "geo.city_name", setter: fld_set}]},
     "location_country": {to:[{field: "geo.country_name", setter: fld_set}]},
     "location_desc": {to:[{field: "geo.name", setter: fld_set}]},
@@ -1962,6 +2388,8 @@ function populate_fields(evt) {
     alternate_datetime(evt);
     if (map_ecs) {
         do_populate(evt, base, ecs_mappings);
+        map_log_level(evt);
+        map_event_category(evt, base);
     }
     if (map_rsa) {
         do_populate(evt, base, rsa_mappings);
@@ -1972,6 +2400,43 @@ function populate_fields(evt) {
     evt.Delete(FIELDS_OBJECT);
 }
 
+// Names for the MESSAGE level, which follows the syslog severity scale.
+var log_level_names = [
+    "emergency",
+    "alert",
+    "critical",
+    "error",
+    "warning",
+    "notice",
+    "informational",
+    "debug",
+];
+
+// Sets log.level from the MESSAGE level (event.severity) unless it was
+// already populated from a captured severity.
+function map_log_level(evt) {
+    if (evt.Get("log.level") != null) return;
+    var level = evt.Get("event.severity");
+    if (level != null && level >= 0 && level < log_level_names.length) {
+        evt.Put("log.level", log_level_names[level]);
+    }
+}
+
+// Sets the ECS categorization fields from the MESSAGE eventcategory code.
+// An outcome already populated from the log takes precedence.
+function map_event_category(evt, base) {
+    var code = base.eventcategory;
+    if (code == null) return;
+    var cat = event_categories[code];
+    if (cat === undefined) return;
+    if (cat.kind !== undefined) evt.Put("event.kind", cat.kind);
+    if (cat.category !== undefined) evt.Put("event.category", cat.category);
+    if (cat.type !== undefined) evt.Put("event.type", cat.type);
+    if (cat.outcome !== undefined && evt.Get("event.outcome") == null) {
+        evt.Put("event.outcome", cat.outcome);
+    }
+}
+
 var datetime_alt_components = [
     {field: "day", fmts: [[dF]]},
     {field: "year", fmts: [[dW]]},
@@ -2050,10 +2515,15 @@ function test() {
     test_tz();
     test_conversions();
     test_mappings();
+    test_log_level();
+    test_event_category();
+    test_dirchk();
     test_url();
     test_calls();
     test_assumptions();
     test_tvm();
+    test_vartypes();
+    test_msgid_select();
     console = saved;
 }
 
@@ -2260,6 +2730,143 @@ function test_mappings() {
     }
 }
 
+function test_log_level() {
+    var cases = [
+        {fields: {"level": "3"}, expected: {"event.severity": 3, "log.level": "error"}},
+        {fields: {"level": "6", "severity": "HIGH"}, expected: {"event.severity": 6, "log.level": "HIGH"}},
+        {fields: {"level": "12"}, expected: {"event.severity": 12, "log.level": null}},
+        {fields: {}, expected: {"event.severity": null, "log.level": null}},
+    ];
+    for (var i = 0; i < cases.length; i++) {
+        var evt = new Event({});
+        do_populate(evt, cases[i].fields, ecs_mappings);
+        map_log_level(evt);
+        for (var key in cases[i].expected) {
+            var got = JSON.stringify(evt.Get(key));
+            var exp = JSON.stringify(cases[i].expected[key]);
+            if (got !== exp) {
+                throw "test test_log_level failed for case " + i + " key " + key
+                    + ". Expected:" + exp
+                    + " Got:" + got;
+            }
+        }
+    }
+}
+
+function test_event_category() {
+    var saved = event_categories;
+    event_categories = {
+        "1401030000": {kind: "event", category: ["authentication"], type: ["start"], outcome: "failure"},
+        "1901000000": {kind: "event"},
+    };
+    var cases = [
+        {
+            fields: {"eventcategory": "1401030000"},
+            expected: {"event.kind": "event", "event.category": ["authentication"], "event.type": ["start"], "event.outcome": "failure"},
+        },
+        {
+            fields: {"eventcategory": "1401030000", "ec_outcome": "Success"},
+            expected: {"event.kind": "event", "event.outcome": "success"},
+        },
+        {
+            fields: {"eventcategory": "1901000000"},
+            expected: {"event.kind": "event", "event.category": null, "event.outcome": null},
+        },
+        {
+            fields: {"eventcategory": "1204000000"},
+            expected: {"event.kind": null, "event.type": null},
+        },
+    ];
+    for (var i = 0; i < cases.length; i++) {
+        var evt = new Event({});
+        do_populate(evt, cases[i].fields, ecs_mappings);
+        map_event_category(evt, cases[i].fields);
+        for (var key in cases[i].expected) {
+            var got = JSON.stringify(evt.Get(key));
+            var exp = JSON.stringify(cases[i].expected[key]);
+            if (got !== exp) {
+                event_categories = saved;
+                throw "test test_event_category failed for case " + i + " key " + key
+                    + ". Expected:" + exp
+                    + " Got:" + got;
+            }
+        }
+    }
+    event_categories = saved;
+}
+
+function test_dirchk() {
+    var saved = local_networks;
+    local_networks = parse_local_networks("10.0.0.0/8, 2001:db8::/32, ::ffff:192.168.0.0/112");
+    var cases = [
+        {args: ["10.1.2.3"], expected: "1", direction: "outbound"},
+        {args: ["8.8.8.8"], expected: "0", direction: "inbound"},
+        {args: ["$OUT", "10.1.2.3", "8.8.8.8"], expected: "1", direction: "outbound"},
+        {args: ["$OUT", "8.8.8.8", "10.1.2.3"], expected: "0", direction: "inbound"},
+        {args: ["$IN", "10.1.2.3", "10.3.2.1"], expected: "0", direction: "internal"},
+        {args: ["$OUT", "10.1.2.3", "10.3.2.1"], expected: "1", direction: "internal"},
+        {args: ["$IN", "8.8.8.8", "8.8.4.4"], expected: "0", direction: "external"},
+        {args: ["$OUT", "2001:db8::1", "::ffff:8.8.8.8"], expected: "1", direction: "outbound"},
+        {args: ["::ffff:10.1.2.3"], expected: "1", direction: "outbound"},
+        {args: ["::ffff:8.8.8.8"], expected: "0", direction: "inbound"},
+        {args: ["192.168.1.1"], expected: "1", direction: "outbound"},
+        {args: ["192.169.1.1"], expected: "0", direction: "inbound"},
+        {args: ["$OUT", "8.8.8.8", "10.1.2.3", "443", "51000"], expected: "1", direction: "outbound"},
+        {args: ["$OUT", "8.8.8.8", "10.1.2.3", "51000", "443"], expected: "0", direction: "inbound"},
+        {args: ["$OUT", "8.8.8.8", "bad"]},
+        {args: ["10.1.2.3", "8.8.8.8"]},
+    ];
+    for (var i = 0; i < cases.length; i++) {
+        var evt = new Event({});
+        var result = DIRCHK(cases[i].args, evt);
+        var direction = evt.Get(FIELDS_PREFIX + "direction");
+        if (result !== cases[i].expected || (cases[i].direction !== undefined && direction !== cases[i].direction)) {
+            local_networks = saved;
+            throw "test test_dirchk failed for case " + i
+                + ". Expected:" + cases[i].expected + "/" + cases[i].direction
+                + " Got:" + result + "/" + direction;
+        }
+    }
+    local_networks = saved;
+}
+
+function test_msgid_select() {
+    var known = msg("known", setc("known", "yes"));
+    var catch_all = match_copy("MESSAGE#CATCHALL", "nwparser.payload", "event_description");
+    var cases = [
+        {
+            select: msgid_select({"A": known}, catch_all),
+            fields: {"nwparser.messageid": "A", "nwparser.payload": "data"},
+            expected: {"nwparser.known": "yes", "nwparser.event_description": null, "log.flags": null},
+        },
+        {
+            select: msgid_select({"A": known}, catch_all),
+            fields: {"nwparser.messageid": "B", "nwparser.payload": "data"},
+            expected: {"nwparser.known": null, "nwparser.event_description": "data", "log.flags": "unknown_messageid"},
+        },
+        {
+            select: msgid_select({"A": known}),
+            fields: {"nwparser.messageid": "B", "nwparser.payload": "data"},
+            expected: {"nwparser.known": null, "nwparser.event_description": null, "log.flags": null},
+        },
+    ];
+    for (var i = 0; i < cases.length; i++) {
+        var evt = new Event({});
+        for (var field in cases[i].fields) {
+            evt.Put(field, cases[i].fields[field]);
+        }
+        cases[i].select(evt);
+        for (var key in cases[i].expected) {
+            var got = evt.Get(key);
+            if (got !== cases[i].expected[key]) {
+                throw "test test_msgid_select failed for case " + i + " key " + key
+                    + ". Expected:" + cases[i].expected[key]
+                    + " Got:" + got;
+            }
+        }
+    }
+}
+
 function copy_name(dst, src) {
     Object.defineProperty(dst, "name", { value: src.name });
     return dst;
@@ -2363,6 +2970,25 @@ function test_calls() {
         pass_test(["0","-","1"], "-1"),
         fail_test(["15","/","3"]),
     ]);
+    test_fn_call(CNVTDOMAIN, [
+        fail_test([]),
+        fail_test(["a", "b"]),
+        pass_test(["(3)www(7)example(3)com(0)"], "www.example.com"),
+        pass_test(["(3)www(7)example(3)com"], "www.example.com"),
+        pass_test([" (2)ab(0) "], "ab"),
+        pass_test(["(4)a(b)(3)net(0)"], "a(b).net"),
+        pass_test(["(10)subdomains(2)co(2)uk(0)"], "subdomains.co.uk"),
+        pass_test(["(0)"], ""),
+        pass_test([""], ""),
+        pass_test(["www.example.com"], "www.example.com"),
+        fail_test(["(3)www(10)example"]),
+        fail_test(["(3)wwwx(3)com"]),
+        fail_test(["(a)www"]),
+        fail_test(["(+3)www"]),
+        fail_test(["(-0)"]),
+        fail_test(["(3www"]),
+        fail_test(["(3)www(0)com"]),
+    ]);
     test_fn_call(STRCAT, [
         pass_test([], ""),
         pass_test(["1"], "1"),
@@ -2397,6 +3023,37 @@ function test_calls() {
     test_fn_call(call_test(STRCAT), [
         pass_test([field("a"), constant("-"), field("c")], "7-11"),
     ]);
+    var regx_test = function(rx) {
+        return function(input) {
+            regx({
+                args: input,
+                regx: rx,
+                dest: FIELDS_PREFIX+"z",
+            })(evt);
+            var result = evt.Get(FIELDS_PREFIX + "z");
+            evt.Delete(FIELDS_PREFIX + "z");
+            return result != null? result : undefined;
+        }
+    }
+    test_fn_call(regx_test({
+        expr: new RegExp("-", "g"),
+        input: 0,
+        replacement: " ",
+    }), [
+        pass_test([constant("-")], " "),
+        pass_test([constant("a-b-c")], "a b c"),
+        fail_test([field("a")]),
+        fail_test([field("missing")]),
+    ]);
+    test_fn_call(regx_test({
+        expr: new RegExp("(Stored|Retrieved)", "g"),
+        input: 1,
+        replacement: "$1",
+        default: constant("other"),
+    }), [
+        pass_test([field("a"), constant("Retrieved")], "$1"),
+        pass_test([constant("Stored"), field("b")], "other"),
+    ]);
 }
 
 function test_assumptions() {
@@ -2495,6 +3152,108 @@ function test_tvm() {
                 "log.flags": null,
             }
         },
+        {
+            // TAGVALMAP from rsaecat.
+            config: {
+                pair_separator: ' ',
+                kv_separator: '=',
+                open_quote: '',
+                close_quote: '',
+                escape: '\\',
+            },
+            mappings: {
+                "agentid": "agent",
+                "fname": "filename",
+                "instantIOCName": "threat_name",
+                "sourceModule": "cs_sourcemodule",
+                "machineScore": "risk_num",
+            },
+            on_success: null,
+            message: "agentid=4a1f fname=C:\\Windows\\System32\\cmd.exe instantIOCName=Run\\ key\\=HKLM sourceModule=C:\\Program\\ Files\\a.exe machineScore=\\=10",
+            expected: {
+                "nwparser.agent": "4a1f",
+                "nwparser.filename": "C:\\Windows\\System32\\cmd.exe",
+                "nwparser.threat_name": "Run key=HKLM",
+                "nwparser.cs_sourcemodule": "C:\\Program Files\\a.exe",
+                "nwparser.risk_num": "=10",
+                "log.flags": null,
+            }
+        },
+        {
+            // Unescaped paths are kept as is.
+            config: {
+                pair_separator: ' ',
+                kv_separator: '=',
+                open_quote: '',
+                close_quote: '',
+                escape: '\\',
+            },
+            mappings: {
+                "fname": "filename",
+                "fsize": "filename_size",
+            },
+            on_success: null,
+            message: "fname=C:\\Windows\\cmd.exe fsize=1024",
+            expected: {
+                "nwparser.filename": "C:\\Windows\\cmd.exe",
+                "nwparser.filename_size": "1024",
+                "log.flags": null,
+            }
+        },
+        {
+            // VARTYPE accepts values.
+            config: {
+                pair_separator: ' ',
+                kv_separator: '=',
+                open_quote: '',
+                close_quote: '',
+                escape: '\\',
+            },
+            mappings: {
+                "fname": "filename",
+                "fsize": "filename_size",
+            },
+            vartypes: {
+                filename_size: new RegExp("^(?:[0-9]+)$", ""),
+            },
+            on_success: processor_chain([
+                                setc("success", "true")
+                            ]),
+            message: "fname=cmd.exe fsize=1024",
+            expected: {
+                "nwparser.filename": "cmd.exe",
+                "nwparser.filename_size": "1024",
+                "nwparser.success": "true",
+                "log.flags": null,
+            }
+        },
+        {
+            // VARTYPE rejects values.
+            config: {
+                pair_separator: ' ',
+                kv_separator: '=',
+                open_quote: '',
+                close_quote: '',
+                escape: '\\',
+            },
+            mappings: {
+                "fname": "filename",
+                "fsize": "filename_size",
+            },
+            vartypes: {
+                filename_size: new RegExp("^(?:[0-9]+)$", ""),
+            },
+            on_success: processor_chain([
+                                setc("success", "true")
+                            ]),
+            message: "fname=cmd.exe fsize=1k",
+            expected: {
+                "nwparser.filename": null,
+                "nwparser.filename_size": null,
+                "nwparser.success": null,
+                "log.flags": "vartype_validation_error",
+            }
+        },
     ];
     var assertEqual = function(evt, key, expected) {
         var value = evt.Get(key);
@@ -2506,9 +3265,58 @@ function test_tvm() {
         var evt = new Event({
             "message": test.message,
         });
+        var saved = vartypes;
+        vartypes = test.vartypes !== undefined? test.vartypes : {};
         processor(evt);
+        vartypes = saved;
         for (var key in test.expected) {
             assertEqual(evt, key, test.expected[key]);
         }
     });
 }
+
+// Tests the VARTYPE feature.
+function test_vartypes() {
+    var saved = vartypes;
+    vartypes = {
+        month: new RegExp("^(?:Jan|Feb|Mar)$", "i"),
+        day: new RegExp("^(?:3[01]|[12][0-9]|0?[1-9])$", ""),
+    };
+    var checks = vartype_checks("%{month->} %{+day/2} %{?month2} %{time} %{*day}");
+    vartypes = saved;
+    if (checks.length !== 3
+        || checks[0].field !== FIELDS_PREFIX + "month"
+        || checks[1].field !== FIELDS_PREFIX + "day"
+        || checks[2].field !== FIELDS_PREFIX + "day") {
+        throw("vartype_checks failed: got " + JSON.stringify(checks));
+    }
+    var check = function(input) {
+        var evt = new Event({});
+        for (var key in input) {
+            evt.Put(FIELDS_PREFIX + key, input[key]);
+        }
+        return check_vartypes(evt, checks);
+    };
+    test_fn_call(check, [
+        pass_test({month: "jan", day: "31"}, true),
+        pass_test({month: "Feb"}, true),
+        pass_test({}, true),
+        pass_test({month: "February", day: "31"}, false),
+        pass_test({month: "Mar", day: "32"}, false),
+    ]);
+
+    var keys = pattern_keys("%{month->} %{+day/2} %{?skip} %{} %{time} %{day}");
+    if (JSON.stringify(keys) !== JSON.stringify([FIELDS_PREFIX + "month", FIELDS_PREFIX + "day", FIELDS_PREFIX + "time"])) {
+        throw("pattern_keys failed: got " + JSON.stringify(keys));
+    }
+    var evt = new Event({});
+    evt.Put(FIELDS_PREFIX + "month", "Jan");
+    var saved = save_fields(evt, keys);
+    evt.Put(FIELDS_PREFIX + "month", "February");
+    evt.Put(FIELDS_PREFIX + "day", "31");
+    restore_fields(evt, saved);
+    if (evt.Get(FIELDS_PREFIX + "month") !== "Jan"
+        || evt.Get(FIELDS_PREFIX + "day") != null) {
+        throw("restore_fields failed: got month=" + evt.Get(FIELDS_PREFIX + "month") + " day=" + evt.Get(FIELDS_PREFIX + "day"));
+    }
+}
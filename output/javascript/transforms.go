@@ -11,6 +11,7 @@ import (
 	"github.com/joeshaw/multierror"
 	"github.com/pkg/errors"
 
+	"github.com/adriansr/nwdevice2filebeat/ecs"
 	"github.com/adriansr/nwdevice2filebeat/parser"
 )
 
@@ -145,6 +146,7 @@ func (p RawJS) Children() []parser.Operation {
 
 type TagValMapCfg struct {
 	*parser.TagValMapSettings
+	Named map[string]*parser.TagValMapSettings
 }
 
 func (p TagValMapCfg) String() string {
@@ -159,15 +161,60 @@ func (p TagValMapCfg) Children() []parser.Operation {
 	return nil
 }
 
+type VarTypesCfg []parser.VarType
+
+func (p VarTypesCfg) String() string {
+	return "VarTypesCfg"
+}
+
+func (p VarTypesCfg) Hashable() string {
+	return fmt.Sprintf("%+v", []parser.VarType(p))
+}
+
+func (p VarTypesCfg) Children() []parser.Operation {
+	return nil
+}
+
+// EventCategoriesCfg is the ECS categorization for the event category codes
+// used by the device, keyed by the code as it appears in MESSAGE.
+type EventCategoriesCfg []ecs.Category
+
+func (p EventCategoriesCfg) String() string {
+	return "EventCategoriesCfg"
+}
+
+func (p EventCategoriesCfg) Hashable() string {
+	return fmt.Sprintf("%+v", []ecs.Category(p))
+}
+
+func (p EventCategoriesCfg) Children() []parser.Operation {
+	return nil
+}
+
+func newEventCategoriesCfg(p *parser.Parser) (cfg EventCategoriesCfg) {
+	for _, code := range p.EventCategories() {
+		if cat, found := p.Config.EventCategories.Lookup(code); found {
+			cat.Code = code
+			cfg = append(cfg, cat)
+		}
+	}
+	return cfg
+}
+
 func adjustTree(p *parser.Parser) (err error) {
 	var file File
 	file.Nodes = append(file.Nodes,
 		RawJS(header),
-		TagValMapCfg{p.TagValMap},
+		TagValMapCfg{p.TagValMap, p.TagValMapsByName},
+		VarTypesCfg(p.VarTypes),
+		newEventCategoriesCfg(p),
 		MainProcessor{inner: []parser.Operation{p.Root}})
 	for _, vm := range p.ValueMaps {
 		file.Nodes = append(file.Nodes, vm)
 	}
+	for _, rx := range p.Regexs {
+		file.Nodes = append(file.Nodes, rx)
+	}
 	p.Root = file
 	return nil
 }
@@ -187,6 +234,9 @@ func adjustFieldNames(p *parser.Parser) (err error) {
 		case parser.ValueMapCall:
 			v.Target = "nwparser." + v.Target
 			return parser.WalkReplace, v
+		case parser.RegexCall:
+			v.Target = "nwparser." + v.Target
+			return parser.WalkReplace, v
 		case parser.SetField:
 			v.Target = "nwparser." + v.Target
 			return parser.WalkReplace, v
@@ -227,15 +277,16 @@ func adjustOverlappingPayload(p *parser.Parser) (err error) {
 }
 
 var supportedJSFunctions = map[string]struct{}{
-	"STRCAT": {},
-	"SYSVAL": {},
-	"HDR":    {},
-	"DIRCHK": {},
-	"DUR":    {},
-	"URL":    {},
-	"CALC":   {},
-	"RMQ":    {},
-	"UTC":    {},
+	"CNVTDOMAIN": {},
+	"STRCAT":     {},
+	"SYSVAL":     {},
+	"HDR":        {},
+	"DIRCHK":     {},
+	"DUR":        {},
+	"URL":        {},
+	"CALC":       {},
+	"RMQ":        {},
+	"UTC":        {},
 }
 
 func checkFunctionCalls(p *parser.Parser) (err error) {
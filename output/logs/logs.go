@@ -37,6 +37,7 @@ type logs struct {
 	tmpFile   *os.File
 	rng       *rand.Rand
 	fieldsGen fieldsGen
+	varTypes  map[string]*regexp.Regexp
 }
 
 func init() {
@@ -73,6 +74,12 @@ func (lg *logs) Generate(p parser.Parser) (err error) {
 	if err != nil {
 		return errors.Wrapf(err, "loading %s", fieldsFile)
 	}
+	lg.varTypes = make(map[string]*regexp.Regexp, len(p.VarTypesByName))
+	for name, vt := range p.VarTypesByName {
+		if lg.varTypes[name], err = regexp.Compile(vt.Expr()); err != nil {
+			return errors.Wrapf(err, "compiling VARTYPE %s", name)
+		}
+	}
 	lg.tmpFile, err = ioutil.TempFile("", "generated-*.log")
 	if err != nil {
 		return err
@@ -474,7 +481,10 @@ type lineComposer struct {
 	fieldsGen   fieldsGen
 	expression  parser.Pattern
 	knownFields fieldHints
+	varTypes    map[string]*regexp.Regexp
 	history     []string
+	// Fields captured in key-value messages whose values must be escaped.
+	escaped map[string]parser.TagValMapSettings
 }
 
 var removeWhitespace = regexp.MustCompile(" +")
@@ -536,6 +546,9 @@ func (lc *lineComposer) Build() (string, error) {
 			if err != nil {
 				return "", errors.Wrapf(err, "getting value for field '%s'", v.Name)
 			}
+			if tvm, found := lc.escaped[v.Name]; found {
+				value = tvm.Escape(value)
+			}
 			sb.WriteString(value)
 		default:
 			return "", errors.Errorf("no support for type %T when building log", v)
@@ -574,10 +587,41 @@ func (lc *lineComposer) valueFor(field string) (value string, err error) {
 	}
 	// Discard used hints
 	lc.knownFields[field] = hints[idx:]
-	if best.Quality() > 0 {
-		return best.Generate(lc.rng, lc.time), nil
+	generate := func() (string, error) {
+		if best.Quality() > 0 {
+			return best.Generate(lc.rng, lc.time), nil
+		}
+		return lc.defaultValueFor(field)
+	}
+	if value, err = generate(); err != nil {
+		return value, err
 	}
-	return lc.defaultValueFor(field)
+	return lc.satisfyVarType(field, value, generate)
+}
+
+// Number of times a value is regenerated when it doesn't match the field's
+// VARTYPE, before resorting to varTypeProbes.
+const maxVarTypeRetries = 8
+
+// satisfyVarType ensures that the value for a field matches the VARTYPE
+// defined for it, if any, otherwise the generated line will be rejected
+// by the runtime.
+func (lc *lineComposer) satisfyVarType(field, value string, generate func() (string, error)) (string, error) {
+	re, found := lc.varTypes[field]
+	if !found || re.MatchString(value) {
+		return value, nil
+	}
+	for i := 0; i < maxVarTypeRetries; i++ {
+		if v, err := generate(); err == nil && re.MatchString(v) {
+			return v, nil
+		}
+	}
+	for _, probe := range varTypeProbes {
+		if v := probe(lc.rng, lc.time); re.MatchString(v) {
+			return v, nil
+		}
+	}
+	return "", errors.Errorf("no value for field %s satisfies VARTYPE %s", field, re)
 }
 
 func (lc *lineComposer) defaultValueFor(field string) (string, error) {
@@ -622,6 +666,7 @@ func (lg *logs) newLine(p parser.Parser, t time.Time) (string, error) {
 		rng:         lg.rng,
 		fieldsGen:   lg.fieldsGen,
 		knownFields: make(fieldHints),
+		varTypes:    lg.varTypes,
 	}
 	if err := state.randomWalk(p.Root); err != nil {
 		return "", errors.Wrapf(err, "error during random walk (historic:%+v)", state.history)
@@ -645,6 +690,14 @@ func (lc *lineComposer) randomWalk(node parser.Operation) error {
 
 	case parser.Match:
 		lc.history = append(lc.history, v.ID)
+		if v.TagValues.IsSet() && v.TagValues.Config.KeyValueEscape != "" {
+			if lc.escaped == nil {
+				lc.escaped = make(map[string]parser.TagValMapSettings)
+			}
+			for _, fld := range v.TagValues.Map {
+				lc.escaped[fld] = v.TagValues.Config
+			}
+		}
 		if err := lc.appendPattern(v.Pattern); err != nil {
 			return err
 		}
@@ -1057,6 +1110,12 @@ func (lc *lineComposer) appendActions(list parser.OpList) error {
 				lc.addHint(fld.Name, newMapKey(vm.Mappings))
 			}
 
+		case parser.RegexCall:
+			// The target is derived from fields already present in the
+			// message, so there's nothing to hint. The regex is only applied
+			// when the runtime validates the generated line.
+			continue
+
 		case parser.URLExtract:
 			lc.addHint(v.Target, urlComponent(v.Component))
 			lc.addHint(v.Source, url{})
@@ -186,3 +186,25 @@ func TestMergeOverlapped(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildEscapesTagValues(t *testing.T) {
+	tvm := parser.TagValMapSettings{
+		PairSeparator:     " ",
+		KeyValueSeparator: "=",
+		KeyValueEscape:    "\\",
+	}
+	lc := lineComposer{
+		expression: parser.Pattern{c("fname="), f("filename"), c(" instantIOCName="), f("threat_name")},
+		knownFields: fieldHints{
+			"filename":    {captured{}, constant(`C:\Windows\cmd.exe`)},
+			"threat_name": {captured{}, constant("Run key=HKLM")},
+		},
+		escaped: map[string]parser.TagValMapSettings{
+			"filename":    tvm,
+			"threat_name": tvm,
+		},
+	}
+	line, err := lc.Build()
+	assert.NoError(t, err)
+	assert.Equal(t, `fname=C:\\Windows\\cmd.exe instantIOCName=Run\ key\=HKLM`, line)
+}
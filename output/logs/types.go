@@ -207,3 +207,22 @@ var makeTimezone = oneOf(
 	"GMT+02:00",
 	"GMT-07:00",
 )
+
+// varTypeProbes are tried in order to find a value that satisfies a VARTYPE
+// when the field's own generator can't produce one. They cover the kind of
+// expressions found in VARTYPE definitions (mostly date components).
+var varTypeProbes = []valueGenerator{
+	func(rng *rand.Rand, t time.Time) string { return t.Month().String()[:3] },
+	func(rng *rand.Rand, t time.Time) string { return t.Month().String() },
+	func(rng *rand.Rand, t time.Time) string { return fmt.Sprintf("%02d", t.Month()) },
+	func(rng *rand.Rand, t time.Time) string { return strconv.Itoa(int(t.Month())) },
+	func(rng *rand.Rand, t time.Time) string { return fmt.Sprintf("%02d", t.Day()) },
+	func(rng *rand.Rand, t time.Time) string { return strconv.Itoa(t.Day()) },
+	func(rng *rand.Rand, t time.Time) string { return t.Weekday().String()[:3] },
+	func(rng *rand.Rand, t time.Time) string { return t.Weekday().String() },
+	func(rng *rand.Rand, t time.Time) string { return t.Format("15:04:05") },
+	func(rng *rand.Rand, t time.Time) string { return strconv.Itoa(t.Year()) },
+	func(rng *rand.Rand, t time.Time) string { return fmt.Sprintf("%02d", t.Year()%100) },
+	makeInt,
+	makeText,
+}
@@ -158,3 +158,70 @@ func (Duration) Children() []Operation {
 func (v Duration) Hashable() string {
 	return DateTime(v).namesHashable("Duration")
 }
+
+// devtsComponents are the components that make up the name of a HEADER devts
+// function, i.e. MDYTS(month,day,year,time), each consuming one argument,
+// and the EVNTTIME formats that are tried for them.
+var devtsComponents = []struct {
+	name    string
+	formats []string
+}{
+	// TS needs to be checked before the single-letter components.
+	{"TS", []string{"%N:%U:%O"}},
+	{"M", []string{"%B", "%G"}},
+	{"D", []string{"%F"}},
+	{"Y", []string{"%W"}},
+}
+
+// newDeviceTimestamp translates the devts attribute of a HEADER into an
+// EVNTTIME call that stores the device timestamp in event_time, the preferred
+// source for @timestamp.
+func newDeviceTimestamp(devts string, location SourceContext) (op Operation, err error) {
+	parsed, err := parseCall(devts, false, location)
+	if err != nil {
+		return nil, err
+	}
+	call, ok := parsed.(Call)
+	if !ok {
+		return nil, errors.Errorf("devts is not a function call: '%s'", devts)
+	}
+	formats := []string{""}
+	var numComponents int
+	for name := call.Function; name != ""; numComponents++ {
+		found := false
+		for _, comp := range devtsComponents {
+			if strings.HasPrefix(name, comp.name) {
+				next := make([]string, 0, len(formats)*len(comp.formats))
+				for _, prefix := range formats {
+					for _, format := range comp.formats {
+						if prefix != "" {
+							format = prefix + " " + format
+						}
+						next = append(next, format)
+					}
+				}
+				formats = next
+				name = name[len(comp.name):]
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.Errorf("unsupported devts function '%s'", call.Function)
+		}
+	}
+	if len(call.Args) != numComponents {
+		return nil, errors.Errorf("devts function '%s' expects %d arguments, got %d",
+			call.Function, numComponents, len(call.Args))
+	}
+	args := make([]Value, 0, len(formats)+len(call.Args))
+	for _, format := range formats {
+		args = append(args, Constant(format))
+	}
+	return Call{
+		SourceContext: location,
+		Function:      "EVNTTIME",
+		Target:        "event_time",
+		Args:          append(args, call.Args...),
+	}, nil
+}
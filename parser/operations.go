@@ -7,6 +7,7 @@ package parser
 import (
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/adriansr/nwdevice2filebeat/util"
@@ -74,8 +75,11 @@ func (c LinearSelect) Hashable() string {
 type MsgIdSelect struct {
 	SourceContext
 	Nodes []Operation
-	//Default int
-	Map map[string]int
+	Map   map[string]int
+	// Default is the catch-all message used for messageids that have no
+	// mapping, or nil if there's none. It's not one of the Children, but
+	// it's visited when walking the tree.
+	Default Operation
 }
 
 func (c MsgIdSelect) Children() []Operation {
@@ -97,7 +101,10 @@ func (c MsgIdSelect) Hashable() string {
 		sb.WriteString(c.Nodes[c.Map[k]].Hashable())
 		sb.WriteByte(',')
 	}
-	//sb.WriteString(fmt.Sprintf("def=%d}", c.Default))
+	if c.Default != nil {
+		sb.WriteString("default:")
+		sb.WriteString(c.Default.Hashable())
+	}
 	return sb.String()
 }
 
@@ -190,6 +197,9 @@ func (m Match) Hashable() string {
 	sb.WriteString("Input:" + m.Input)
 	sb.WriteString(",Pattern:" + m.Pattern.Hashable())
 	sb.WriteString("PayloadField:" + m.PayloadField)
+	if m.TagValues.IsSet() {
+		sb.WriteString("TagValMap:" + m.TagValues.MapName)
+	}
 	sb.WriteString("OnSuccess:")
 	sb.WriteString(OpList(m.OnSuccess).Hashable())
 	sb.WriteByte('}')
@@ -341,10 +351,17 @@ func (v ValueMapCall) Hashable() string {
 	return sb.String()
 }
 
-// TODO: Regex is unsupported.
+// Regex is a REGX definition. When called, every match of Expr in the
+// parameter at position Input is replaced by Replacement. When the expression
+// doesn't match, the Default value is used instead (if any).
 type Regex struct {
 	SourceContext
-	Name string
+	Name        string
+	Params      []string
+	Input       int
+	Expr        string
+	Replacement string
+	Default     *Value
 }
 
 func (v Regex) Children() []Operation {
@@ -355,6 +372,41 @@ func (v Regex) Hashable() string {
 	var sb strings.Builder
 	sb.WriteString("Regex{Name:")
 	sb.WriteString(v.Name)
+	sb.WriteString(",Params:")
+	sb.WriteString(strings.Join(v.Params, ","))
+	sb.WriteString(",Input:")
+	sb.WriteString(strconv.Itoa(v.Input))
+	sb.WriteString(",Expr:")
+	sb.WriteString(v.Expr)
+	sb.WriteString(",Replacement:")
+	sb.WriteString(v.Replacement)
+	if v.Default != nil {
+		sb.WriteString(",Default:")
+		sb.WriteString((*v.Default).Hashable())
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+type RegexCall struct {
+	SourceContext
+	Target string
+	Name   string
+	Args   []Operation
+}
+
+func (v RegexCall) Children() []Operation {
+	return v.Args
+}
+
+func (v RegexCall) Hashable() string {
+	var sb strings.Builder
+	sb.WriteString("RegexCall{Target:")
+	sb.WriteString(v.Target)
+	sb.WriteString(",Name:")
+	sb.WriteString(v.Name)
+	sb.WriteString(",Args:")
+	sb.WriteString(OpList(v.Args).Hashable())
 	sb.WriteByte('}')
 	return sb.String()
 }
@@ -6,6 +6,9 @@ package parser
 
 import (
 	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -20,16 +23,21 @@ type Parser struct {
 
 	Description model.DeviceHeader
 	Version     model.Version
+	INI         *model.DeviceINI
 
 	TagValMap *TagValMapSettings
 	ValueMaps []ValueMap
 	Regexs    []Regex
+	SumDatas  []SumData
+	VarTypes  []VarType
 	Headers   []header
 	Messages  []message
 
-	ValueMapsByName map[string]*ValueMap
-	RegexsByName    map[string]*Regex
-	Root            Operation
+	ValueMapsByName  map[string]*ValueMap
+	RegexsByName     map[string]*Regex
+	VarTypesByName   map[string]*VarType
+	TagValMapsByName map[string]*TagValMapSettings
+	Root             Operation
 
 	warnings *util.Warnings
 }
@@ -39,25 +47,9 @@ func New(dev model.Device, cfg config.Config, warnings *util.Warnings) (p Parser
 	p.Config = cfg
 	p.Description = dev.Description
 	p.Version = dev.Version
+	p.INI = dev.INI
 
-	var unsupported []string
-
-	if len(dev.Regexs) > 0 {
-		unsupported = append(unsupported, "REGEX")
-	}
-	if len(dev.SumDatas) > 0 {
-		unsupported = append(unsupported, "SUMDATA")
-	}
-	if len(dev.VarTypes) > 0 {
-		//unsupported = append(unsupported, "VARTYPE")
-		//
-		// Let's just warn when VARTYPE entries are found.
-		warnings.Add(dev.VarTypes[0].Pos(), "Unsupported feature VARTYPE")
-	}
-	if len(unsupported) > 0 {
-		return p, errors.Errorf("found unsupported features: %v", unsupported)
-	}
-	if p.TagValMap, err = p.processTagValMap(dev.TagValMaps); err != nil {
+	if p.TagValMap, p.TagValMapsByName, err = p.processTagValMaps(dev.TagValMaps); err != nil {
 		return p, err
 	}
 	if p.ValueMaps, p.ValueMapsByName, err = p.processValueMaps(dev.ValueMaps); err != nil {
@@ -66,6 +58,12 @@ func New(dev model.Device, cfg config.Config, warnings *util.Warnings) (p Parser
 	if p.Regexs, p.RegexsByName, err = p.processRegexs(dev.Regexs); err != nil {
 		return p, err
 	}
+	if p.VarTypes, p.VarTypesByName, err = p.processVarTypes(dev.VarTypes); err != nil {
+		return p, err
+	}
+	if p.SumDatas, err = p.processSumDatas(dev.SumDatas); err != nil {
+		return p, err
+	}
 	if p.Headers, err = p.processHeaders(dev.Headers); err != nil {
 		return p, err
 	}
@@ -83,24 +81,34 @@ func New(dev model.Device, cfg config.Config, warnings *util.Warnings) (p Parser
 		SourceContext: SourceContext(dev.Description.Pos()),
 	}
 	hNodes := make([]Operation, 0, len(p.Headers))
-	for idx, h := range p.Headers {
-		match := Match{
-			SourceContext: SourceContext(h.pos),
-			ID:            fmt.Sprintf("HEADER#%d:%s", idx, h.id2),
-			Input:         "message",
-			Pattern:       h.content,
-			PayloadField:  h.payloadField,
-		}
-		match.OnSuccess = append(match.OnSuccess, SetField{
-			SourceContext: match.SourceContext,
-			Target:        "header_id",
-			Value:         []Operation{Constant(h.id2)},
-		})
-		if h.messageID != nil {
-			match.OnSuccess = append(match.OnSuccess, h.messageID)
+	// Headers marked with prioritize are tried before the rest. Otherwise
+	// XML order is kept.
+	for _, prioritized := range []bool{true, false} {
+		for idx, h := range p.Headers {
+			if h.prioritize != prioritized {
+				continue
+			}
+			match := Match{
+				SourceContext: SourceContext(h.pos),
+				ID:            fmt.Sprintf("HEADER#%d:%s", idx, h.id2),
+				Input:         "message",
+				Pattern:       h.content,
+				PayloadField:  h.payloadField,
+			}
+			match.OnSuccess = append(match.OnSuccess, SetField{
+				SourceContext: match.SourceContext,
+				Target:        "header_id",
+				Value:         []Operation{Constant(h.id2)},
+			})
+			if h.messageID != nil {
+				match.OnSuccess = append(match.OnSuccess, h.messageID)
+			}
+			if h.devts != nil {
+				match.OnSuccess = append(match.OnSuccess, h.devts)
+			}
+			match.OnSuccess = append(match.OnSuccess, h.functions...)
+			hNodes = append(hNodes, match)
 		}
-		match.OnSuccess = append(match.OnSuccess, h.functions...)
-		hNodes = append(hNodes, match)
 	}
 
 	msgNode, err := p.makeMessagesNode(p.Messages)
@@ -125,6 +133,36 @@ func New(dev model.Device, cfg config.Config, warnings *util.Warnings) (p Parser
 	return p, nil
 }
 
+// EventCategories returns the distinct eventcategory codes used by the
+// device's messages, sorted.
+func (p *Parser) EventCategories() (codes []string) {
+	seen := make(map[string]bool)
+	for _, msg := range p.Messages {
+		if code := msg.eventcategory; code != "" && !seen[code] {
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Tables returns the distinct INI tables referenced by the tableid attribute
+// of the device's messages, sorted by ID.
+func (p *Parser) Tables() (tables []model.INITable) {
+	seen := make(map[int]bool)
+	for _, msg := range p.Messages {
+		if table := msg.table; table != nil && !seen[table.ID] {
+			seen[table.ID] = true
+			tables = append(tables, *table)
+		}
+	}
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].ID < tables[j].ID
+	})
+	return tables
+}
+
 func (p *Parser) makeMessagesNode(msgs []message) (Operation, error) {
 	var keysInOrder []string
 	byID2 := make(map[string][]Operation)
@@ -136,7 +174,7 @@ func (p *Parser) makeMessagesNode(msgs []message) (Operation, error) {
 			Pattern:       msg.content,
 			TagValues:     msg.tags,
 		}
-		match.OnSuccess = make([]Operation, 0, 2+len(msg.functions))
+		match.OnSuccess = make([]Operation, 0, 3+len(msg.functions))
 		if msg.eventcategory != "" {
 			match.OnSuccess = append(match.OnSuccess, SetField{
 				SourceContext: match.SourceContext,
@@ -144,6 +182,13 @@ func (p *Parser) makeMessagesNode(msgs []message) (Operation, error) {
 				Value:         []Operation{Constant(msg.eventcategory)},
 			})
 		}
+		if msg.level != "" {
+			match.OnSuccess = append(match.OnSuccess, SetField{
+				SourceContext: match.SourceContext,
+				Target:        "level",
+				Value:         []Operation{Constant(msg.level)},
+			})
+		}
 		// This causes every message to be unique. Outputs must remember to
 		// extract this value to deduplicate properly.
 		if !p.Config.Opt.StripMessageID1 && msg.id1 != "" {
@@ -175,16 +220,34 @@ func (p *Parser) makeMessagesNode(msgs []message) (Operation, error) {
 			elem = list[0]
 		} else {
 			elem = LinearSelect{
-				Nodes: list,
+				Nodes: p.checkShadowedMessages(k, list),
 			}
 		}
 		msgIdSelect.Nodes = append(msgIdSelect.Nodes, elem)
 		msgIdSelect.Map[k] = counter
 		counter++
 	}
+	if p.Config.Fixes.CatchAll {
+		msgIdSelect.Default = newCatchAllMessage()
+	}
 	return msgIdSelect, nil
 }
 
+// CatchAllMessageID is the ID of the generic message used for unknown
+// messageids.
+const CatchAllMessageID = "MESSAGE#CATCHALL"
+
+// newCatchAllMessage returns the generic message for unknown messageids. It
+// keeps the whole payload in event_description, which is mapped to ECS
+// message.
+func newCatchAllMessage() Match {
+	return Match{
+		ID:      CatchAllMessageID,
+		Input:   "payload",
+		Pattern: Pattern{Field{Name: "event_description"}},
+	}
+}
+
 func (p *Parser) processValueMaps(input []*model.ValueMap) (output []ValueMap, byName map[string]*ValueMap, err error) {
 	byName = make(map[string]*ValueMap, len(input))
 	output = make([]ValueMap, len(input))
@@ -206,9 +269,9 @@ func (p *Parser) processRegexs(input []*model.RegX) (output []Regex, byName map[
 	byName = make(map[string]*Regex, len(input))
 	output = make([]Regex, len(input))
 	for idx, xml := range input {
-		regex := Regex{
-			SourceContext: SourceContext(xml.Pos()),
-			Name:          xml.Name,
+		regex, err := newRegex(xml)
+		if err != nil {
+			return output, byName, errors.Wrapf(err, "error parsing REGX at %s", xml.Pos())
 		}
 		if byName[regex.Name] != nil {
 			return output, byName, errors.Errorf("duplicated REGX name at %s", xml.Pos())
@@ -222,7 +285,7 @@ func (p *Parser) processRegexs(input []*model.RegX) (output []Regex, byName map[
 func (p *Parser) processHeaders(input []*model.Header) (output []header, err error) {
 	output = make([]header, len(input))
 	for idx, xml := range input {
-		vm, err := newHeader(xml)
+		vm, err := p.newHeader(xml)
 		if err != nil {
 			return output, errors.Wrapf(err, "error parsing HEADER at %s", xml.Pos())
 		}
@@ -291,6 +354,50 @@ func (p *Parser) newValueMap(xml *model.ValueMap) (vm ValueMap, err error) {
 	return vm, nil
 }
 
+// Matches REGX expressions in the form:
+// <parm>(regular expression) ? 'replacement'
+var regexExprRegex = regexp.MustCompile(`^\s*<([^>]+)>\s*\((.*)\)\s*\?\s*(?:'(.*)'|"(.*)")\s*$`)
+
+func newRegex(xml *model.RegX) (rx Regex, err error) {
+	rx.SourceContext = SourceContext(xml.Pos())
+	rx.Name = xml.Name
+	for _, parm := range strings.Split(xml.Parms, ",") {
+		if parm = strings.TrimSpace(parm); parm != "" {
+			rx.Params = append(rx.Params, parm)
+		}
+	}
+	if len(rx.Params) == 0 {
+		return rx, errors.New("no parameters defined")
+	}
+	groups := regexExprRegex.FindStringSubmatch(xml.Expr)
+	if groups == nil {
+		return rx, errors.Errorf("unsupported expression '%s'", xml.Expr)
+	}
+	rx.Input = -1
+	for idx, parm := range rx.Params {
+		if parm == groups[1] {
+			rx.Input = idx
+			break
+		}
+	}
+	if rx.Input == -1 {
+		return rx, errors.Errorf("expression references unknown parameter '%s'", groups[1])
+	}
+	if _, err = regexp.Compile(groups[2]); err != nil {
+		return rx, errors.Wrapf(err, "cannot compile expression '%s'", groups[2])
+	}
+	rx.Expr = groups[2]
+	rx.Replacement = groups[3] + groups[4]
+	if !valueMapNullValues[xml.Default] {
+		v, err := newValue(xml.Default, false)
+		if err != nil {
+			return rx, errors.Wrapf(err, "cannot parse REGX default value '%s'", xml.Default)
+		}
+		rx.Default = &v
+	}
+	return rx, nil
+}
+
 type header struct {
 	pos       util.XMLPos
 	id2       string
@@ -298,12 +405,17 @@ type header struct {
 	functions []Operation
 	content   Pattern
 
+	// Set from the prioritize attribute.
+	prioritize bool
+	// Translated from the devts attribute, sets the event time.
+	devts Operation
+
 	// This field is not in the XML. We're adding this information to help
 	// capture payload when the payload overlaps part of the header.
 	payloadField string
 }
 
-func newHeader(xml *model.Header) (h header, err error) {
+func (p *Parser) newHeader(xml *model.Header) (h header, err error) {
 	// This appears in all the messages.
 	if xml.ID2 == "" {
 		return h, errors.Errorf("empty id2 attribute")
@@ -334,7 +446,17 @@ func newHeader(xml *model.Header) (h header, err error) {
 	if h.functions, err = parseFunctions(xml.Functions, SourceContext(xml.Pos())); err != nil {
 		return h, errors.Wrap(err, "error parsing functions")
 	}
-	return h, err
+	if prioritize := strings.TrimSpace(xml.Prioritize); prioritize != "" {
+		if h.prioritize, err = strconv.ParseBool(prioritize); err != nil {
+			p.warnings.Addf(xml.Pos(), "ignored invalid HEADER prioritize='%s'", xml.Prioritize)
+		}
+	}
+	if devts := strings.TrimSpace(xml.Devts); devts != "" {
+		if h.devts, err = newDeviceTimestamp(devts, SourceContext(xml.Pos())); err != nil {
+			return h, errors.Wrap(err, "error parsing devts")
+		}
+	}
+	return h, nil
 }
 
 type message struct {
@@ -342,9 +464,11 @@ type message struct {
 	id1           string
 	id2           string
 	eventcategory string
+	level         string
 	functions     []Operation
 	content       Pattern
 	tags          TagValues
+	table         *model.INITable
 }
 
 func (m message) String() string {
@@ -371,6 +495,20 @@ func (p *Parser) newMessage(xml *model.Message) (m message, err error) {
 		eventcategory: xml.EventCategory,
 	}
 
+	if level := strings.TrimSpace(xml.Level); level != "" {
+		if _, err := strconv.Atoi(level); err == nil {
+			m.level = level
+		} else {
+			p.warnings.Addf(xml.Pos(), "ignored non-numeric MESSAGE level='%s'", xml.Level)
+		}
+	}
+	if xml.TableID != "" && p.INI != nil {
+		if table, found := p.INI.Table(xml.TableID); found {
+			m.table = &table
+		} else {
+			p.warnings.Addf(xml.Pos(), "MESSAGE %s references unknown tableid='%s'", xml.ID2, xml.TableID)
+		}
+	}
 	if m.content, err = ParsePatternWithAlternatives(xml.Content); err != nil {
 		return m, errors.Wrap(err, "error parsing content")
 	}
@@ -382,14 +520,25 @@ func (p *Parser) newMessage(xml *model.Message) (m message, err error) {
 	if xml.TagVal != "" && !isTagVal {
 		return m, fmt.Errorf("unsupported tagval='%s'", xml.TagVal)
 	}
-	if isTagVal && p.TagValMap == nil {
-		return m, fmt.Errorf("unexpected tagval='%s'", xml.TagVal)
+	if !isTagVal {
+		if xml.TagValMap != "" {
+			p.warnings.Addf(xml.Pos(), "ignored tagvalmap='%s' in MESSAGE without tagval", xml.TagValMap)
+		}
+		return m, nil
+	}
+	settings := p.TagValMap
+	if xml.TagValMap != "" {
+		var found bool
+		if settings, found = p.TagValMapsByName[xml.TagValMap]; !found {
+			return m, fmt.Errorf("reference to unknown tagvalmap='%s'", xml.TagValMap)
+		}
 	}
-	if isTagVal {
-		m.tags, err = newTagValues(m.content, *p.TagValMap)
-		err = errors.Wrap(err, "parsing tagval")
+	if settings == nil {
+		return m, fmt.Errorf("unexpected tagval='%s'", xml.TagVal)
 	}
-	return m, err
+	m.tags, err = newTagValues(m.content, *settings)
+	m.tags.MapName = xml.TagValMap
+	return m, errors.Wrap(err, "parsing tagval")
 }
 
 func parseFunctions(s string, loc SourceContext) (calls []Operation, err error) {
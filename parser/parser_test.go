@@ -0,0 +1,194 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adriansr/nwdevice2filebeat/config"
+	"github.com/adriansr/nwdevice2filebeat/model"
+	"github.com/adriansr/nwdevice2filebeat/util"
+)
+
+func TestHeaderPrioritize(t *testing.T) {
+	dev := model.Device{
+		Headers: []*model.Header{
+			{ID1: "0001", ID2: "0001", Content: "<messageid> <!payload>"},
+			{ID1: "0002", ID2: "0002", Content: "A <messageid> <!payload>", Prioritize: "true"},
+			{ID1: "0003", ID2: "0003", Content: "B <messageid> <!payload>", Prioritize: "false"},
+			{ID1: "0004", ID2: "0004", Content: "C <messageid> <!payload>", Prioritize: "yes"},
+			{ID1: "0005", ID2: "0005", Content: "D <messageid> <!payload>", Prioritize: "1"},
+		},
+		Messages: []*model.Message{
+			{ID1: "msg", ID2: "msg", Content: "<data>"},
+		},
+	}
+	warnings := util.NewWarnings(10)
+	p, err := New(dev, config.Config{}, &warnings)
+	if !assert.NoError(t, err) {
+		return
+	}
+	var ids []string
+	root := p.Root.(Chain)
+	for _, node := range root.Nodes[0].(LinearSelect).Nodes {
+		ids = append(ids, node.(Match).ID)
+	}
+	assert.Equal(t, []string{
+		"HEADER#1:0002",
+		"HEADER#4:0005",
+		"HEADER#0:0001",
+		"HEADER#2:0003",
+		"HEADER#3:0004",
+	}, ids)
+	if assert.Len(t, warnings.Message, 1) {
+		assert.Contains(t, warnings.Message[0].Text, "ignored invalid HEADER prioritize='yes'")
+	}
+}
+
+func TestNewDeviceTimestamp(t *testing.T) {
+	for _, test := range []struct {
+		devts    string
+		expected Operation
+		err      string
+	}{
+		{
+			devts: "MDYTS(month,day,year,time)",
+			expected: Call{
+				Function: "EVNTTIME",
+				Target:   "event_time",
+				Args: []Value{
+					Constant("%B %F %W %N:%U:%O"),
+					Constant("%G %F %W %N:%U:%O"),
+					Field{Name: "month"},
+					Field{Name: "day"},
+					Field{Name: "year"},
+					Field{Name: "time"},
+				},
+			},
+		},
+		{
+			devts: "YDTS(year,day,time)",
+			expected: Call{
+				Function: "EVNTTIME",
+				Target:   "event_time",
+				Args: []Value{
+					Constant("%W %F %N:%U:%O"),
+					Field{Name: "year"},
+					Field{Name: "day"},
+					Field{Name: "time"},
+				},
+			},
+		},
+		{
+			devts: "MDYTS(month,day,year)",
+			err:   "expects 4 arguments, got 3",
+		},
+		{
+			devts: "MDXTS(month,day,year,time)",
+			err:   "unsupported devts function 'MDXTS'",
+		},
+	} {
+		t.Run(test.devts, func(t *testing.T) {
+			op, err := newDeviceTimestamp(test.devts, SourceContext{})
+			if test.err != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), test.err)
+				}
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, test.expected, op)
+			}
+		})
+	}
+}
+
+func TestParser_Tables(t *testing.T) {
+	dev := model.Device{
+		INI: &model.DeviceINI{
+			Tables: map[int]model.INITable{
+				77: {ID: 77, Name: "Firewall", RealTable: "firewall"},
+				87: {ID: 87, Name: "VPN", RealTable: "vpn"},
+			},
+		},
+		Headers: []*model.Header{
+			{ID1: "0001", ID2: "0001", Content: "<messageid> <!payload>"},
+		},
+		Messages: []*model.Message{
+			{ID1: "msg1", ID2: "msg1", Content: "a <data>", TableID: "87"},
+			{ID1: "msg2", ID2: "msg2", Content: "b <data>", TableID: "77"},
+			{ID1: "msg3", ID2: "msg3", Content: "c <data>", TableID: "87"},
+			{ID1: "msg4", ID2: "msg4", Content: "d <data>"},
+			{ID1: "msg5", ID2: "msg5", Content: "e <data>", TableID: "12"},
+		},
+	}
+	warnings := util.NewWarnings(10)
+	p, err := New(dev, config.Config{}, &warnings)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []model.INITable{
+		{ID: 77, Name: "Firewall", RealTable: "firewall"},
+		{ID: 87, Name: "VPN", RealTable: "vpn"},
+	}, p.Tables())
+	if assert.Len(t, warnings.Message, 1) {
+		assert.Contains(t, warnings.Message[0].Text, "unknown tableid='12'")
+	}
+}
+
+func TestCatchAllMessage(t *testing.T) {
+	dev := model.Device{
+		Headers: []*model.Header{
+			{ID1: "0001", ID2: "0001", Content: "<messageid> <!payload>"},
+		},
+		Messages: []*model.Message{
+			{ID1: "msg", ID2: "msg", Content: "<data>"},
+		},
+	}
+	for _, catchAll := range []bool{false, true} {
+		warnings := util.NewWarnings(10)
+		p, err := New(dev, config.Config{Fixes: config.Fixes{CatchAll: catchAll}}, &warnings)
+		if !assert.NoError(t, err) {
+			return
+		}
+		sel := p.Root.(Chain).Nodes[1].(MsgIdSelect)
+		assert.Len(t, sel.Map, 1)
+		assert.Len(t, sel.Nodes, 1)
+		if !catchAll {
+			assert.Nil(t, sel.Default)
+			continue
+		}
+		if assert.NotNil(t, sel.Default) {
+			assert.Equal(t, CatchAllMessageID, sel.Default.(Match).ID)
+		}
+	}
+}
+
+func TestWalkCatchAllMessage(t *testing.T) {
+	p := Parser{
+		Root: Chain{Nodes: []Operation{
+			MsgIdSelect{
+				Nodes:   []Operation{Match{ID: "msg"}},
+				Map:     map[string]int{"msg": 0},
+				Default: newCatchAllMessage(),
+			},
+		}},
+	}
+	rename := func(node Operation) (WalkAction, Operation) {
+		if m, ok := node.(Match); ok {
+			m.ID += "-renamed"
+			return WalkReplace, m
+		}
+		return WalkContinue, nil
+	}
+	for _, walk := range []func(WalkFn) WalkAction{p.Walk, p.WalkPostOrder} {
+		walk(rename)
+	}
+	sel := p.Root.(Chain).Nodes[0].(MsgIdSelect)
+	assert.Equal(t, "msg-renamed-renamed", sel.Nodes[0].(Match).ID)
+	assert.Equal(t, CatchAllMessageID+"-renamed-renamed", sel.Default.(Match).ID)
+}
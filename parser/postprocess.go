@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"log"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/joeshaw/multierror"
@@ -62,8 +63,9 @@ var transforms = PostprocessGroup{
 	Actions: []Action{
 		// Replaces a Call() to a MalueMap with a ValueMapCall.
 		{"translate VALUEMAP references", convertValueMapReferences},
+		// Replaces a Call() to a REGX with a RegexCall.
+		{"translate REGX references", convertRegexReferences},
 		{"translate calls", translateCalls},
-		{"strip REGX calls", stripRegex},
 		{"strip SYSVAL calls", pruneSysval},
 		{"strip same-field copy", pruneUnnecessaryCopies},
 		{"remove no-ops in function lists", removeNoops},
@@ -118,6 +120,7 @@ var validations = PostprocessGroup{
 		{"detect bad dissect patterns", detectBrokenDissectPatterns},
 		{"detect unknown function calls", detectUnknownFunctionCalls},
 		{"detect unnamed matches", detectUnnamedMatches},
+		{"detect unreachable messages", detectUnreachableMessages},
 	},
 }
 
@@ -209,8 +212,8 @@ func checkFunctionsArity(parser *Parser) (err error) {
 			min, max := 0, 0
 			if info, ok := KnownFunctions[fn]; ok {
 				min, max = info.MinArgs, info.MaxArgs
-			} else if _, ok := parser.RegexsByName[fn]; ok {
-				min, max = 1, 1
+			} else if rx, ok := parser.RegexsByName[fn]; ok {
+				min, max = len(rx.Params), len(rx.Params)
 			} else if _, ok := parser.ValueMapsByName[fn]; ok {
 				min, max = 1, 1
 			} else {
@@ -370,6 +373,23 @@ func translateCalls(parser *Parser) (err error) {
 					Source:    param[1],
 					Component: cp,
 				}
+
+			case "DIRCHK":
+				switch len(call.Args) {
+				case 1:
+				case 3, 5:
+					// The first argument is the default direction, $IN or $OUT,
+					// which is passed as a constant.
+					if fld, ok := call.Args[0].(Field); ok && (fld.Name == "$IN" || fld.Name == "$OUT") {
+						call.Args = append([]Value{Constant(fld.Name)}, call.Args[1:]...)
+						return WalkReplace, call
+					}
+					err = errors.Errorf("at %s: DIRCHK first argument must be $IN or $OUT: %v", call.Source(), call.Hashable())
+					return WalkCancel, nil
+				default:
+					err = errors.Errorf("at %s: DIRCHK call has an unsupported number of arguments: %v", call.Source(), call.Hashable())
+					return WalkCancel, nil
+				}
 			}
 		}
 		return WalkContinue, nil
@@ -377,16 +397,31 @@ func translateCalls(parser *Parser) (err error) {
 	return err
 }
 
-func stripRegex(parser *Parser) error {
-	parser.Walk(func(node Operation) (action WalkAction, operation Operation) {
+func convertRegexReferences(parser *Parser) error {
+	var errs multierror.Errors
+	parser.Walk(func(node Operation) (WalkAction, Operation) {
 		if call, ok := node.(Call); ok {
-			if _, found := parser.RegexsByName[call.Function]; found {
-				return WalkReplace, Noop{}
+			if rx, found := parser.RegexsByName[call.Function]; found {
+				if len(call.Args) != len(rx.Params) {
+					errs = append(errs, errors.Errorf("at %s: call to REGX %s must have %d arguments but has %d",
+						call.Source(), rx.Name, len(rx.Params), len(call.Args)))
+					return WalkReplace, Noop{}
+				}
+				args := make([]Operation, len(call.Args))
+				for idx, arg := range call.Args {
+					args[idx] = arg
+				}
+				return WalkReplace, RegexCall{
+					SourceContext: call.SourceContext,
+					Target:        call.Target,
+					Name:          rx.Name,
+					Args:          args,
+				}
 			}
 		}
 		return WalkContinue, nil
 	})
-	return nil
+	return errs.Err()
 }
 
 func removeNoops(parser *Parser) error {
@@ -1165,7 +1200,7 @@ type FunctionInfo struct {
 var KnownFunctions = map[string]FunctionInfo{
 	"CALC":       {MinArgs: 3, MaxArgs: 3},
 	"CNVTDOMAIN": {MinArgs: 1, MaxArgs: 1},
-	"DIRCHK":     {MinArgs: 1},
+	"DIRCHK":     {MinArgs: 1, MaxArgs: 5},
 	"DUR":        {MinArgs: 3},
 	"EVNTTIME":   {MinArgs: 2},
 	"RMQ":        {MinArgs: 1, MaxArgs: 1},
@@ -1334,6 +1369,160 @@ func detectBrokenDissectPatterns(parser *Parser) error {
 	return errs.Err()
 }
 
+// This reports MESSAGEs whose id2 can't be produced by any HEADER, and
+// constant messageids set by HEADERs that have no MESSAGE. Each HEADER can
+// produce a set of messageids, represented as a pattern of constants and
+// fields, where fields match any value.
+func detectUnreachableMessages(parser *Parser) error {
+	type constantID struct {
+		header Match
+		id     string
+	}
+	var producers []Pattern
+	var constants []constantID
+	var sel *MsgIdSelect
+	parser.Walk(func(node Operation) (action WalkAction, operation Operation) {
+		switch v := node.(type) {
+		case MsgIdSelect:
+			sel = &v
+			return WalkSkip, nil
+		case Match:
+			if patternCapturesField(v.Pattern, "messageid") {
+				producers = append(producers, Pattern{Field{Name: "messageid"}})
+			}
+			for _, op := range v.OnSuccess {
+				pattern, ok := messageIDPattern(op)
+				if !ok {
+					continue
+				}
+				producers = append(producers, pattern)
+				if len(pattern) == 1 {
+					if ct, isConst := pattern[0].(Constant); isConst {
+						constants = append(constants, constantID{header: v, id: string(ct)})
+					}
+				}
+			}
+		}
+		return WalkContinue, nil
+	})
+	if sel == nil {
+		return nil
+	}
+	for _, ct := range constants {
+		if _, found := sel.Map[ct.id]; !found {
+			parser.warnings.Addf(ct.header.Source(), "%s sets messageid '%s' which has no MESSAGE", ct.header.ID, ct.id)
+		}
+	}
+	ids := make([]string, 0, len(sel.Map))
+	for id2 := range sel.Map {
+		ids = append(ids, id2)
+	}
+	sort.Strings(ids)
+	for _, id2 := range ids {
+		reachable := false
+		for _, pattern := range producers {
+			if reachable = matchesMessageIDPattern(pattern, id2); reachable {
+				break
+			}
+		}
+		if reachable {
+			continue
+		}
+		for _, msg := range outermostMatches(sel.Nodes[sel.Map[id2]]) {
+			parser.warnings.Addf(msg.Source(), "%s is unreachable: no HEADER produces messageid '%s'", msg.ID, id2)
+		}
+	}
+	return nil
+}
+
+// messageIDPattern returns the pattern of messageids that the given operation
+// can produce, or false if it doesn't set the messageid.
+func messageIDPattern(op Operation) (Pattern, bool) {
+	var args []Operation
+	switch v := op.(type) {
+	case SetField:
+		if v.Target != "messageid" {
+			return nil, false
+		}
+		args = v.Value
+	case Call:
+		if v.Target != "messageid" {
+			return nil, false
+		}
+		if v.Function != "STRCAT" {
+			return Pattern{Field{Name: "messageid"}}, true
+		}
+		for _, arg := range v.Args {
+			args = append(args, arg)
+		}
+	default:
+		return nil, false
+	}
+	var pattern Pattern
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case Constant:
+			pattern = append(pattern, v)
+		case Field:
+			pattern = append(pattern, v)
+		default:
+			return Pattern{Field{Name: "messageid"}}, true
+		}
+	}
+	return pattern, true
+}
+
+// matchesMessageIDPattern returns if the given pattern can produce id. Fields
+// in the pattern match any value, including an empty one.
+func matchesMessageIDPattern(pattern Pattern, id string) bool {
+	if len(pattern) == 0 {
+		return id == ""
+	}
+	switch v := pattern[0].(type) {
+	case Constant:
+		return strings.HasPrefix(id, string(v)) &&
+			matchesMessageIDPattern(pattern[1:], id[len(v):])
+	case Field:
+		for n := 0; n <= len(id); n++ {
+			if matchesMessageIDPattern(pattern[1:], id[n:]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func patternCapturesField(pattern Pattern, name string) bool {
+	for _, elem := range pattern {
+		switch v := elem.(type) {
+		case Field:
+			if v.Name == name {
+				return true
+			}
+		case Alternatives:
+			for _, alt := range v {
+				if patternCapturesField(alt, name) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// outermostMatches returns the matches in the given subtree, without
+// descending into them.
+func outermostMatches(op Operation) (matches []Match) {
+	walk(&op, func(node Operation) (action WalkAction, operation Operation) {
+		if match, ok := node.(Match); ok {
+			matches = append(matches, match)
+			return WalkSkip, nil
+		}
+		return WalkContinue, nil
+	})
+	return matches
+}
+
 func injectCapturesInAltsPattern(pattern Pattern) (Pattern, error) {
 	var lastField Value
 	var remove []int
@@ -5,12 +5,14 @@
 package parser
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/adriansr/nwdevice2filebeat/config"
 	"github.com/adriansr/nwdevice2filebeat/model"
+	"github.com/adriansr/nwdevice2filebeat/util"
 )
 
 func testPostprocessTree(act func(*Parser) error, input Operation) (output Operation, err error) {
@@ -685,3 +687,73 @@ func TestRemoveExtraSpace(t *testing.T) {
 		assert.Equal(t, test.expected, result)
 	}
 }
+
+// reachabilityWarnings returns the text of the warnings, without the ones
+// about shadowed MESSAGEs.
+func reachabilityWarnings(warnings util.Warnings) (texts []string) {
+	for _, w := range warnings.Message {
+		if !strings.Contains(w.Text, " is shadowed by ") {
+			texts = append(texts, w.Text)
+		}
+	}
+	return texts
+}
+
+func TestDetectUnreachableMessages(t *testing.T) {
+	dev := model.Device{
+		Headers: []*model.Header{
+			{ID1: "0001", ID2: "0001", MessageID: "STRCAT(part1,'_',part2)", Content: "A <part1> <part2>: <!payload>"},
+			{ID1: "0002", ID2: "0002", MessageID: "STRCAT('BIT9','_','events')", Content: "B: <!payload>"},
+			{ID1: "0003", ID2: "0003", MessageID: "STRCAT('ORPHAN')", Content: "C: <!payload>"},
+		},
+		Messages: []*model.Message{
+			{ID1: "X_Y", ID2: "X_Y", Content: "<data>"},
+			{ID1: "BIT9_events", ID2: "BIT9_events", Content: "<data>"},
+			{ID1: "DEAD", ID2: "DEAD", Content: "<data>"},
+			{ID1: "DEAD2", ID2: "DEAD", Content: "<data> <data2>"},
+		},
+	}
+	warnings := util.NewWarnings(10)
+	_, err := New(dev, config.Config{}, &warnings)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{
+		"HEADER#2:0003 sets messageid 'ORPHAN' which has no MESSAGE",
+		"MESSAGE#2:DEAD is unreachable: no HEADER produces messageid 'DEAD'",
+		"MESSAGE#3:DEAD2 is unreachable: no HEADER produces messageid 'DEAD'",
+	}, reachabilityWarnings(warnings))
+
+	// A header that captures the messageid can produce any id.
+	dev.Headers = append(dev.Headers, &model.Header{ID1: "0004", ID2: "0004", Content: "<messageid> <!payload>"})
+	warnings = util.NewWarnings(10)
+	_, err = New(dev, config.Config{}, &warnings)
+	if !assert.NoError(t, err) {
+		return
+	}
+	if texts := reachabilityWarnings(warnings); assert.Len(t, texts, 1) {
+		assert.Contains(t, texts[0], "ORPHAN")
+	}
+}
+
+func TestMatchesMessageIDPattern(t *testing.T) {
+	pattern := Pattern{Field{Name: "a"}, Constant("_"), Field{Name: "b"}}
+	for _, test := range []struct {
+		pattern  Pattern
+		id       string
+		expected bool
+	}{
+		{pattern: Pattern{Constant("ID")}, id: "ID", expected: true},
+		{pattern: Pattern{Constant("ID")}, id: "ID2"},
+		{pattern: pattern, id: "X_Y", expected: true},
+		{pattern: pattern, id: "_", expected: true},
+		{pattern: pattern, id: "A__B", expected: true},
+		{pattern: pattern, id: "XY"},
+		{pattern: Pattern{Constant("BIT9_"), Field{Name: "a"}}, id: "BIT9_events", expected: true},
+		{pattern: Pattern{Constant("BIT9_"), Field{Name: "a"}}, id: "Bit9_events"},
+	} {
+		t.Run(test.pattern.String()+"/"+test.id, func(t *testing.T) {
+			assert.Equal(t, test.expected, matchesMessageIDPattern(test.pattern, test.id))
+		})
+	}
+}
@@ -0,0 +1,120 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adriansr/nwdevice2filebeat/model"
+)
+
+func TestNewRegex(t *testing.T) {
+	defValue := func(v Value) *Value {
+		return &v
+	}
+	for _, testCase := range []struct {
+		title    string
+		input    model.RegX
+		expected Regex
+		err      bool
+	}{
+		{
+			title: "replace with quoted constant",
+			input: model.RegX{
+				Name:    "removeDash",
+				Parms:   "parm1",
+				Default: "$NONE",
+				Expr:    "<parm1>(-) ? ' '",
+			},
+			expected: Regex{
+				Name:        "removeDash",
+				Params:      []string{"parm1"},
+				Expr:        "-",
+				Replacement: " ",
+			},
+		},
+		{
+			title: "nested groups",
+			input: model.RegX{
+				Name:    "action2Proto",
+				Parms:   "parm1",
+				Default: "$NONE",
+				Expr:    "<parm1>((Stored|Retrieved)) ? 'FTP'",
+			},
+			expected: Regex{
+				Name:        "action2Proto",
+				Params:      []string{"parm1"},
+				Expr:        "(Stored|Retrieved)",
+				Replacement: "FTP",
+			},
+		},
+		{
+			title: "second parameter and default",
+			input: model.RegX{
+				Name:    "rx",
+				Parms:   "a, b",
+				Default: "unknown",
+				Expr:    `<b>(\s+)?" "`,
+			},
+			expected: Regex{
+				Name:        "rx",
+				Params:      []string{"a", "b"},
+				Input:       1,
+				Expr:        `\s+`,
+				Replacement: " ",
+				Default:     defValue(Constant("unknown")),
+			},
+		},
+		{
+			title: "unknown parameter",
+			input: model.RegX{
+				Name:  "rx",
+				Parms: "parm1",
+				Expr:  "<parm2>(-) ? ' '",
+			},
+			err: true,
+		},
+		{
+			title: "no parameters",
+			input: model.RegX{
+				Name: "rx",
+				Expr: "<parm1>(-) ? ' '",
+			},
+			err: true,
+		},
+		{
+			title: "bad regular expression",
+			input: model.RegX{
+				Name:  "rx",
+				Parms: "parm1",
+				Expr:  "<parm1>([a-) ? ' '",
+			},
+			err: true,
+		},
+		{
+			title: "unsupported expression",
+			input: model.RegX{
+				Name:  "rx",
+				Parms: "parm1",
+				Expr:  "parm1 =~ /-/",
+			},
+			err: true,
+		},
+	} {
+		t.Run(testCase.title, func(t *testing.T) {
+			result, err := newRegex(&testCase.input)
+			if testCase.err {
+				assert.Error(t, err)
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, testCase.expected, result)
+		})
+	}
+}
@@ -0,0 +1,260 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package parser
+
+import (
+	"log"
+	"strings"
+)
+
+// Maximum number of patterns a pattern with alternatives can expand to before
+// it's excluded from shadowing analysis.
+const maxShadowExpansions = 64
+
+// checkShadowedMessages analyzes the MESSAGEs that share an id2, in the order
+// they are tried, and warns about messages that can never match because an
+// earlier message always matches their input. If the ReorderShadowed fix is
+// enabled, more specific messages are moved first. Returns the (maybe
+// reordered) list.
+func (p *Parser) checkShadowedMessages(id2 string, list []Operation) []Operation {
+	n := len(list)
+	expanded := make([][]Pattern, n)
+	for idx, op := range list {
+		if match, ok := op.(Match); ok && len(match.Pattern) > 0 && !match.TagValues.IsSet() {
+			expanded[idx] = expandAlternatives(match.Pattern, maxShadowExpansions)
+		}
+	}
+	subsumes := make([][]bool, n)
+	for i := range subsumes {
+		subsumes[i] = make([]bool, n)
+		for j := range subsumes[i] {
+			subsumes[i][j] = i != j && patternsSubsume(expanded[i], expanded[j])
+		}
+	}
+	order := make([]int, n)
+	for idx := range order {
+		order[idx] = idx
+	}
+	if p.Config.Fixes.ReorderShadowed {
+		if order = specificFirst(subsumes); !isIdentity(order) {
+			reordered := make([]Operation, n)
+			for pos, idx := range order {
+				reordered[pos] = list[idx]
+			}
+			log.Printf("INFO - Reordered %d MESSAGEs with id2 '%s' to avoid shadowing", n, id2)
+			list = reordered
+		}
+	}
+	for pos, j := range order {
+		for _, i := range order[:pos] {
+			if subsumes[i][j] {
+				shadowed, by := list[pos].(Match), list[indexOf(order, i)].(Match)
+				p.warnings.Addf(shadowed.Source(), "%s is shadowed by %s at %s",
+					shadowed.ID, by.ID, by.Source())
+				break
+			}
+		}
+	}
+	return list
+}
+
+// specificFirst returns a stable ordering where a pattern comes before the
+// patterns that strictly subsume it. As subsumption checks are conservative,
+// they can form a cycle. In that case, the first pattern not yet placed is
+// taken to break it.
+func specificFirst(subsumes [][]bool) []int {
+	n := len(subsumes)
+	used := make([]bool, n)
+	order := make([]int, 0, n)
+	for len(order) < n {
+		placed := false
+		for x := 0; x < n && !placed; x++ {
+			if used[x] {
+				continue
+			}
+			ready := true
+			for y := 0; y < n && ready; y++ {
+				ready = used[y] || !subsumes[x][y] || subsumes[y][x]
+			}
+			if ready {
+				used[x] = true
+				order = append(order, x)
+				placed = true
+			}
+		}
+		if !placed {
+			for x := 0; x < n; x++ {
+				if !used[x] {
+					used[x] = true
+					order = append(order, x)
+					break
+				}
+			}
+		}
+	}
+	return order
+}
+
+func isIdentity(order []int) bool {
+	for pos, idx := range order {
+		if pos != idx {
+			return false
+		}
+	}
+	return true
+}
+
+func indexOf(list []int, value int) int {
+	for idx, v := range list {
+		if v == value {
+			return idx
+		}
+	}
+	return -1
+}
+
+// expandAlternatives returns all the patterns without alternatives that
+// a pattern can match, or nil if the pattern can't be analyzed or has more
+// than limit expansions.
+func expandAlternatives(pattern Pattern, limit int) []Pattern {
+	result := []Pattern{nil}
+	for _, elem := range pattern {
+		switch v := elem.(type) {
+		case Constant, Field:
+			for idx := range result {
+				result[idx] = append(result[idx], v)
+			}
+		case Alternatives:
+			var next []Pattern
+			for _, alt := range v {
+				for _, branch := range expandAlternatives(alt, limit) {
+					for _, prefix := range result {
+						p := make(Pattern, 0, len(prefix)+len(branch))
+						next = append(next, append(append(p, prefix...), branch...))
+					}
+				}
+			}
+			if len(next) == 0 || len(next) > limit {
+				return nil
+			}
+			result = next
+		default:
+			return nil
+		}
+	}
+	for idx := range result {
+		result[idx] = result[idx].SquashConstants()
+	}
+	return result
+}
+
+// patternsSubsume returns if every input matched by one of the patterns in b
+// is also matched by one of the patterns in a.
+func patternsSubsume(a, b []Pattern) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return false
+	}
+	for _, pb := range b {
+		found := false
+		for _, pa := range a {
+			if found = subsumes(pa, pb); found {
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// shadowToken is an element of a pattern, as seen by subsumes: either a
+// literal character or a field capture. Captures keep the constant that
+// terminates them, which the captured value can't contain.
+type shadowToken struct {
+	chr     byte
+	capture bool
+	term    string
+}
+
+func tokenize(pattern Pattern) (tokens []shadowToken) {
+	for idx, elem := range pattern {
+		switch v := elem.(type) {
+		case Constant:
+			for _, chr := range []byte(v) {
+				tokens = append(tokens, shadowToken{chr: chr})
+			}
+		case Field:
+			tok := shadowToken{capture: true}
+			if idx+1 < len(pattern) {
+				if ct, ok := pattern[idx+1].(Constant); ok {
+					tok.term = string(ct)
+				}
+			}
+			tokens = append(tokens, tok)
+		}
+	}
+	return tokens
+}
+
+// subsumes returns if pattern a is known to match every input that pattern b
+// matches. Both patterns must be free of alternatives. Fields are matched as
+// dissect does: a field captures a non-empty value up to the first
+// occurrence of the following constant. It's conservative: when unsure, it
+// returns false.
+func subsumes(a, b Pattern) bool {
+	return subsumesAt(a, tokenize(b))
+}
+
+func subsumesAt(a Pattern, b []shadowToken) bool {
+	if len(a) == 0 {
+		return len(b) == 0
+	}
+	switch v := a[0].(type) {
+	case Constant:
+		if !hasLiteralPrefix(b, string(v)) {
+			return false
+		}
+		return subsumesAt(a[1:], b[len(v):])
+	case Field:
+		if len(a) == 1 {
+			return len(b) > 0
+		}
+		ct, ok := a[1].(Constant)
+		if !ok {
+			return false
+		}
+		sep := string(ct)
+		// When only a capture follows the separator, stopping at an earlier
+		// separator inside a captured value still matches.
+		last := len(a) == 3
+		if _, ok := a[len(a)-1].(Field); !ok {
+			last = false
+		}
+		// Non-empty capture.
+		for pos := 1; pos < len(b); pos++ {
+			if prev := b[pos-1]; !last && prev.capture && (prev.term == "" || !strings.Contains(sep, prev.term)) {
+				// The captured value could contain the separator.
+				return false
+			}
+			if hasLiteralPrefix(b[pos:], sep) {
+				return subsumesAt(a[2:], b[pos+len(sep):])
+			}
+		}
+	}
+	return false
+}
+
+func hasLiteralPrefix(tokens []shadowToken, prefix string) bool {
+	if len(tokens) < len(prefix) {
+		return false
+	}
+	for idx := range []byte(prefix) {
+		if tokens[idx].capture || tokens[idx].chr != prefix[idx] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,141 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adriansr/nwdevice2filebeat/config"
+	"github.com/adriansr/nwdevice2filebeat/model"
+	"github.com/adriansr/nwdevice2filebeat/util"
+)
+
+func TestSubsumes(t *testing.T) {
+	for _, test := range []struct {
+		a, b     string
+		expected bool
+	}{
+		{a: "<a>", b: "user <b> logged in", expected: true},
+		{a: "user <a> logged in", b: "user <b> logged in", expected: true},
+		{a: "user <a> <b>", b: "user <c> logged in from <d>", expected: true},
+		{a: "user <a> logged in", b: "user <b> logged out"},
+		{a: "user <a> logged in", b: "user <b>"},
+		{a: "<a> <b>", b: "<c>"},
+		// b's first capture can contain ':', which would end a's capture early.
+		{a: "<a>: <b> end", b: "<c> - <d>: x end"},
+		{a: "<a>: <b>", b: "<c> - <d>: x", expected: true},
+		{a: "<a>: <b> end", b: "<c>: <d> end", expected: true},
+		{a: "<a>-{<b>/<c>|<b>} to <d>", b: "<e>-<f> to <g>", expected: true},
+		{a: "<a>-<b> to <d>", b: "<e>-{<f>/<c>|<f>} to <g>", expected: true},
+		{a: "<a>-<b>/<c> to <d>", b: "<e>-<f> to <g>"},
+		{a: "src={<a>|<a>:<b>} <c>", b: "src=<a>:<b> <c>", expected: true},
+	} {
+		t.Run(test.a+" / "+test.b, func(t *testing.T) {
+			a, err := ParsePatternWithAlternatives(test.a)
+			if !assert.NoError(t, err) {
+				return
+			}
+			b, err := ParsePatternWithAlternatives(test.b)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, test.expected, patternsSubsume(
+				expandAlternatives(a, maxShadowExpansions),
+				expandAlternatives(b, maxShadowExpansions)))
+		})
+	}
+}
+
+func TestShadowedMessages(t *testing.T) {
+	dev := model.Device{
+		XMLPath: "test",
+		Headers: []*model.Header{
+			{ID1: "0001", ID2: "0001", Content: "<messageid>: <!payload>"},
+		},
+		Messages: []*model.Message{
+			{ID1: "login:generic", ID2: "login", Content: "user <username> <action>"},
+			{ID1: "login:from", ID2: "login", Content: "user <username> logged in from <saddr>"},
+			{ID1: "login:dup", ID2: "login", Content: "user <user> <result>"},
+			{ID1: "logout", ID2: "logout", Content: "user <username> logged out"},
+		},
+	}
+	ids := func(p *Parser) (result []string) {
+		sel := p.Root.(Chain).Nodes[1].(MsgIdSelect)
+		for _, node := range sel.Nodes[sel.Map["login"]].(LinearSelect).Nodes {
+			result = append(result, node.(Match).ID)
+		}
+		return result
+	}
+	warnings := util.NewWarnings(10)
+	p, err := New(dev, config.Config{}, &warnings)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"MESSAGE#0:login:generic", "MESSAGE#1:login:from", "MESSAGE#2:login:dup"}, ids(&p))
+	if assert.Len(t, warnings.Message, 2) {
+		assert.Equal(t, "MESSAGE#1:login:from is shadowed by MESSAGE#0:login:generic at (unknown)", warnings.Message[0].Text)
+		assert.Equal(t, "MESSAGE#2:login:dup is shadowed by MESSAGE#0:login:generic at (unknown)", warnings.Message[1].Text)
+	}
+
+	warnings = util.NewWarnings(10)
+	p, err = New(dev, config.Config{Fixes: config.Fixes{ReorderShadowed: true}}, &warnings)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"MESSAGE#1:login:from", "MESSAGE#0:login:generic", "MESSAGE#2:login:dup"}, ids(&p))
+	// Identical patterns can't be fixed by reordering.
+	if assert.Len(t, warnings.Message, 1) {
+		assert.Equal(t, "MESSAGE#2:login:dup is shadowed by MESSAGE#0:login:generic at (unknown)", warnings.Message[0].Text)
+	}
+}
+
+func TestSpecificFirst(t *testing.T) {
+	for _, test := range []struct {
+		title    string
+		subsumes [][]bool
+		expected []int
+	}{
+		{
+			title: "unrelated",
+			subsumes: [][]bool{
+				{false, false},
+				{false, false},
+			},
+			expected: []int{0, 1},
+		},
+		{
+			title: "generic first",
+			subsumes: [][]bool{
+				{false, true, false},
+				{false, false, false},
+				{false, false, false},
+			},
+			expected: []int{1, 0, 2},
+		},
+		{
+			title: "equivalent",
+			subsumes: [][]bool{
+				{false, true},
+				{true, false},
+			},
+			expected: []int{0, 1},
+		},
+		{
+			title: "cycle",
+			subsumes: [][]bool{
+				{false, true, false},
+				{false, false, true},
+				{true, false, false},
+			},
+			expected: []int{0, 2, 1},
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			assert.Equal(t, test.expected, specificFirst(test.subsumes))
+		})
+	}
+}
@@ -0,0 +1,58 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package parser
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/adriansr/nwdevice2filebeat/model"
+)
+
+// SumData is a SUMDATA definition. It describes how events are summarised
+// into a bucket, aggregated by the Key (and optional SubKey) field, while
+// accumulating the given Fields.
+//
+// It doesn't affect parsing, it's only kept as metadata for the generated
+// outputs.
+type SumData struct {
+	SourceContext
+	Bucket string
+	Key    string
+	SubKey string
+	Fields []string
+}
+
+func (p *Parser) processSumDatas(input []*model.SumData) (output []SumData, err error) {
+	output = make([]SumData, len(input))
+	for idx, xml := range input {
+		if output[idx], err = newSumData(xml); err != nil {
+			return output, errors.Wrapf(err, "error parsing SUMDATA at %s", xml.Pos())
+		}
+	}
+	return output, nil
+}
+
+func newSumData(xml *model.SumData) (sd SumData, err error) {
+	sd = SumData{
+		SourceContext: SourceContext(xml.Pos()),
+		Bucket:        strings.TrimSpace(xml.Bucket),
+		Key:           strings.TrimSpace(xml.Key),
+		SubKey:        strings.TrimSpace(xml.SubKey),
+	}
+	if sd.Bucket == "" {
+		return sd, errors.New("bucket attribute is empty")
+	}
+	if sd.Key == "" {
+		return sd, errors.New("key attribute is empty")
+	}
+	for _, fld := range strings.Split(xml.Fields, ",") {
+		if fld = strings.TrimSpace(fld); fld != "" {
+			sd.Fields = append(sd.Fields, fld)
+		}
+	}
+	return sd, nil
+}
@@ -0,0 +1,88 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adriansr/nwdevice2filebeat/model"
+)
+
+func TestNewSumData(t *testing.T) {
+	for _, testCase := range []struct {
+		title    string
+		input    model.SumData
+		expected SumData
+		err      bool
+	}{
+		{
+			title: "key and fields",
+			input: model.SumData{
+				Bucket: "NIC_B_FW_ADDR_ACCOUNTING",
+				Key:    "saddr",
+				Fields: "sbytes,duration",
+			},
+			expected: SumData{
+				Bucket: "NIC_B_FW_ADDR_ACCOUNTING",
+				Key:    "saddr",
+				Fields: []string{"sbytes", "duration"},
+			},
+		},
+		{
+			title: "subkey and spaces",
+			input: model.SumData{
+				Bucket: " NIC_B_DEVICES ",
+				Key:    "$DEVICE",
+				SubKey: "daddr",
+				Fields: "0, 0 ,1,",
+			},
+			expected: SumData{
+				Bucket: "NIC_B_DEVICES",
+				Key:    "$DEVICE",
+				SubKey: "daddr",
+				Fields: []string{"0", "0", "1"},
+			},
+		},
+		{
+			title: "no fields",
+			input: model.SumData{
+				Bucket: "NIC_B_FW_PORT_ACCOUNTING",
+				Key:    "dport",
+			},
+			expected: SumData{
+				Bucket: "NIC_B_FW_PORT_ACCOUNTING",
+				Key:    "dport",
+			},
+		},
+		{
+			title: "missing bucket",
+			input: model.SumData{
+				Key: "dport",
+			},
+			err: true,
+		},
+		{
+			title: "missing key",
+			input: model.SumData{
+				Bucket: "NIC_B_FW_PORT_ACCOUNTING",
+			},
+			err: true,
+		},
+	} {
+		t.Run(testCase.title, func(t *testing.T) {
+			result, err := newSumData(&testCase.input)
+			if testCase.err {
+				assert.Error(t, err)
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, testCase.expected, result)
+		})
+	}
+}
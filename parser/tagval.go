@@ -12,6 +12,7 @@ import (
 	"strings"
 
 	"github.com/adriansr/nwdevice2filebeat/model"
+	"github.com/adriansr/nwdevice2filebeat/util"
 )
 
 // TagValMapSettings holds information about the special characters used to
@@ -31,11 +32,11 @@ type TagValMapSettings struct {
 	// CloseQuote is the character used at the end of a quoted string.
 	CloseQuote string
 
-	// KeyValueEscape is the character that escapes the characters in
-	// KeyValueSeparator so that they can appear in a value without terminating
-	// it.
-	// Or not, not sure at all how this works.
-	//KeyValueEscape string
+	// KeyValueEscape is the character that escapes the separators so that
+	// they can appear inside a key or value (escapeValueDelim). The escape
+	// character itself is escaped by doubling it. Any other occurrence of it
+	// is part of the value, as in a Windows path.
+	KeyValueEscape string
 }
 
 // TODO: Completely unsure about this defaults
@@ -44,19 +45,38 @@ var defaults = TagValMapSettings{
 	//PairSeparator:     " ",
 	//OpenQuote:         "",
 	//CloseQuote:        "",
-	//KeyValueEscape:    "\\",
 }
 
-func (p *Parser) processTagValMap(input []*model.TagValMap) (output *TagValMapSettings, err error) {
-	switch len(input) {
-	case 0:
-		return
-	case 1:
-	default:
-		return nil, fmt.Errorf("at %s: more than one TAGVALMAP defined",
-			input[1].Pos())
+// processTagValMaps returns the settings for the unnamed TAGVALMAP, which is
+// used by default, and for the named ones, which are selected by MESSAGEs
+// through the tagvalmap attribute.
+func (p *Parser) processTagValMaps(input []*model.TagValMap) (output *TagValMapSettings, byName map[string]*TagValMapSettings, err error) {
+	var unnamed util.XMLPos
+	for _, def := range input {
+		settings, err := newTagValMapSettings(*def)
+		if err != nil {
+			return nil, nil, err
+		}
+		if def.Name == "" {
+			if output != nil {
+				return nil, nil, fmt.Errorf("at %s: more than one unnamed TAGVALMAP defined (previous at %s)",
+					def.Pos(), unnamed)
+			}
+			output, unnamed = settings, def.Pos()
+			continue
+		}
+		if byName == nil {
+			byName = make(map[string]*TagValMapSettings)
+		}
+		if _, found := byName[def.Name]; found {
+			return nil, nil, fmt.Errorf("at %s: duplicated TAGVALMAP name '%s'", def.Pos(), def.Name)
+		}
+		byName[def.Name] = settings
 	}
-	def := *input[0]
+	return output, byName, nil
+}
+
+func newTagValMapSettings(def model.TagValMap) (*TagValMapSettings, error) {
 	old, err := loadOldSettings(def)
 	if err != nil {
 		return nil, err
@@ -69,7 +89,7 @@ func (p *Parser) processTagValMap(input []*model.TagValMap) (output *TagValMapSe
 	if !ok {
 		return nil, fmt.Errorf("at %s: Can't parse TAGVALMAP: conflicting delimiter values", def.Pos())
 	}
-	return &settings, err
+	return &settings, nil
 }
 
 var delimiterRegex = regexp.MustCompile("(.[^ ]*) ")
@@ -112,7 +132,7 @@ func loadNewSettings(tvm model.TagValMap) (settings TagValMapSettings, err error
 	settings = TagValMapSettings{
 		PairSeparator:     tvm.PairDelimiter,
 		KeyValueSeparator: tvm.ValueDelimiter,
-		//KeyValueEscape:    tvm.EscapeValueDelimt,
+		KeyValueEscape:    tvm.EscapeValueDelimt,
 	}
 	switch len(tvm.Encapsulator) {
 	case 0:
@@ -165,9 +185,84 @@ func merge(old, new, def TagValMapSettings) (out TagValMapSettings, ok bool) {
 	return out, true
 }
 
+// Escape returns the value with the separators and the escape character
+// escaped, so that it can be embedded in a key-value message.
+func (s TagValMapSettings) Escape(value string) string {
+	if s.KeyValueEscape == "" {
+		return value
+	}
+	var sb strings.Builder
+	for pos := 0; pos < len(value); {
+		if n := s.escapedLen(value[pos:]); n > 0 {
+			sb.WriteString(s.KeyValueEscape)
+			sb.WriteString(value[pos : pos+n])
+			pos += n
+			continue
+		}
+		sb.WriteByte(value[pos])
+		pos++
+	}
+	return sb.String()
+}
+
+// Unescape reverses Escape. Escape characters not followed by a separator or
+// another escape character are kept.
+func (s TagValMapSettings) Unescape(value string) string {
+	if s.KeyValueEscape == "" || !strings.Contains(value, s.KeyValueEscape) {
+		return value
+	}
+	var sb strings.Builder
+	for pos := 0; pos < len(value); {
+		if strings.HasPrefix(value[pos:], s.KeyValueEscape) {
+			next := pos + len(s.KeyValueEscape)
+			if n := s.escapedLen(value[next:]); n > 0 {
+				sb.WriteString(value[next : next+n])
+				pos = next + n
+				continue
+			}
+		}
+		sb.WriteByte(value[pos])
+		pos++
+	}
+	return sb.String()
+}
+
+// IndexUnescaped returns the index of the first occurrence of sep in str that
+// is not escaped, or -1 if not present.
+func (s TagValMapSettings) IndexUnescaped(str, sep string) int {
+	if s.KeyValueEscape == "" {
+		return strings.Index(str, sep)
+	}
+	for pos := 0; pos < len(str); {
+		if strings.HasPrefix(str[pos:], s.KeyValueEscape) {
+			pos += len(s.KeyValueEscape)
+			pos += s.escapedLen(str[pos:])
+			continue
+		}
+		if strings.HasPrefix(str[pos:], sep) {
+			return pos
+		}
+		pos++
+	}
+	return -1
+}
+
+// escapedLen returns the length of the escapable sequence at the start of
+// str, or zero if it doesn't start with one.
+func (s TagValMapSettings) escapedLen(str string) int {
+	for _, seq := range []string{s.KeyValueEscape, s.PairSeparator, s.KeyValueSeparator} {
+		if seq != "" && strings.HasPrefix(str, seq) {
+			return len(seq)
+		}
+	}
+	return 0
+}
+
 type TagValues struct {
 	Map    map[string]string
 	Config TagValMapSettings
+	// MapName is the name of the TAGVALMAP used, empty for the default one.
+	MapName string
 }
 
 func newTagValues(pattern Pattern, set TagValMapSettings) (tvm TagValues, err error) {
@@ -0,0 +1,223 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adriansr/nwdevice2filebeat/config"
+	"github.com/adriansr/nwdevice2filebeat/model"
+	"github.com/adriansr/nwdevice2filebeat/util"
+)
+
+func TestProcessTagValMapEscape(t *testing.T) {
+	// TAGVALMAP from rsaecat.
+	var p Parser
+	settings, _, err := p.processTagValMaps([]*model.TagValMap{
+		{
+			Delimiter:         " ",
+			EscapeValueDelimt: "\\",
+		},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, &TagValMapSettings{
+		PairSeparator:     " ",
+		KeyValueSeparator: "=",
+		KeyValueEscape:    "\\",
+	}, settings)
+}
+
+func TestTagValMapSettings_Escape(t *testing.T) {
+	rsaecat := TagValMapSettings{
+		PairSeparator:     " ",
+		KeyValueSeparator: "=",
+		KeyValueEscape:    "\\",
+	}
+	for _, test := range []struct {
+		title    string
+		settings TagValMapSettings
+		value    string
+		escaped  string
+	}{
+		{
+			title:    "no escape",
+			settings: TagValMapSettings{PairSeparator: " ", KeyValueSeparator: "="},
+			value:    "a=b c",
+			escaped:  "a=b c",
+		},
+		{
+			title:    "plain",
+			settings: rsaecat,
+			value:    "MachineIOC",
+			escaped:  "MachineIOC",
+		},
+		{
+			title:    "separators",
+			settings: rsaecat,
+			value:    "Run key=HKLM added",
+			escaped:  `Run\ key\=HKLM\ added`,
+		},
+		{
+			title:    "escape char",
+			settings: rsaecat,
+			value:    `C:\Windows\cmd.exe`,
+			escaped:  `C:\\Windows\\cmd.exe`,
+		},
+		{
+			title: "multi-char separator",
+			settings: TagValMapSettings{
+				PairSeparator:     "||",
+				KeyValueSeparator: ":",
+				KeyValueEscape:    "^",
+			},
+			value:   "a||b|c:d^e",
+			escaped: "a^||b|c^:d^^e",
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			assert.Equal(t, test.escaped, test.settings.Escape(test.value))
+			assert.Equal(t, test.value, test.settings.Unescape(test.escaped))
+		})
+	}
+	// Escape characters that don't precede a separator are kept.
+	assert.Equal(t, `C:\Windows\cmd.exe`, rsaecat.Unescape(`C:\Windows\cmd.exe`))
+	assert.Equal(t, `trailing\`, rsaecat.Unescape(`trailing\`))
+}
+
+func TestTagValMapSettings_IndexUnescaped(t *testing.T) {
+	rsaecat := TagValMapSettings{
+		PairSeparator:     " ",
+		KeyValueSeparator: "=",
+		KeyValueEscape:    "\\",
+	}
+	for _, test := range []struct {
+		str      string
+		sep      string
+		expected int
+	}{
+		{str: "key=value", sep: "=", expected: 3},
+		{str: `k\=ey=value`, sep: "=", expected: 5},
+		{str: `Run\ key`, sep: " ", expected: -1},
+		{str: `C:\\ next`, sep: " ", expected: 4},
+		{str: `C:\Windows x`, sep: " ", expected: 10},
+		{str: `trailing\`, sep: " ", expected: -1},
+	} {
+		t.Run(test.str, func(t *testing.T) {
+			assert.Equal(t, test.expected, rsaecat.IndexUnescaped(test.str, test.sep))
+		})
+	}
+	assert.Equal(t, 2, TagValMapSettings{}.IndexUnescaped(`k\=ey=value`, "="))
+}
+
+func TestProcessTagValMaps(t *testing.T) {
+	for _, test := range []struct {
+		title   string
+		input   []*model.TagValMap
+		def     *TagValMapSettings
+		byName  map[string]*TagValMapSettings
+		wantErr string
+	}{
+		{
+			title: "none",
+		},
+		{
+			title: "default and named",
+			input: []*model.TagValMap{
+				{PairDelimiter: " ", ValueDelimiter: "="},
+				{Name: "csv", PairDelimiter: ",", ValueDelimiter: ":"},
+			},
+			def: &TagValMapSettings{PairSeparator: " ", KeyValueSeparator: "="},
+			byName: map[string]*TagValMapSettings{
+				"csv": {PairSeparator: ",", KeyValueSeparator: ":"},
+			},
+		},
+		{
+			title: "only named",
+			input: []*model.TagValMap{
+				{Name: "csv", PairDelimiter: ","},
+			},
+			byName: map[string]*TagValMapSettings{
+				"csv": {PairSeparator: ",", KeyValueSeparator: "="},
+			},
+		},
+		{
+			title: "two unnamed",
+			input: []*model.TagValMap{
+				{PairDelimiter: " "},
+				{PairDelimiter: ","},
+			},
+			wantErr: "more than one unnamed TAGVALMAP",
+		},
+		{
+			title: "duplicated name",
+			input: []*model.TagValMap{
+				{Name: "csv", PairDelimiter: ","},
+				{Name: "csv", PairDelimiter: ";"},
+			},
+			wantErr: "duplicated TAGVALMAP name 'csv'",
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			var p Parser
+			def, byName, err := p.processTagValMaps(test.input)
+			if test.wantErr != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), test.wantErr)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.def, def)
+			assert.Equal(t, test.byName, byName)
+		})
+	}
+}
+
+func TestMessageTagValMap(t *testing.T) {
+	dev := model.Device{
+		TagValMaps: []*model.TagValMap{
+			{PairDelimiter: " ", ValueDelimiter: "="},
+			{Name: "csv", PairDelimiter: ",", ValueDelimiter: "="},
+		},
+		Headers: []*model.Header{
+			{ID1: "0001", ID2: "0001", Content: "<messageid> <!payload>"},
+		},
+		Messages: []*model.Message{
+			{ID1: "space", ID2: "space", TagVal: "true", Content: "a=<fa> b=<fb>"},
+			{ID1: "comma", ID2: "comma", TagVal: "true", TagValMap: "csv", Content: "a=<fa>,b=<fb>"},
+			{ID1: "plain", ID2: "plain", TagValMap: "csv", Content: "<data>"},
+		},
+	}
+	warnings := util.NewWarnings(10)
+	p, err := New(dev, config.Config{}, &warnings)
+	if !assert.NoError(t, err) {
+		return
+	}
+	tags := make(map[string]TagValues)
+	for _, msg := range p.Messages {
+		tags[msg.id1] = msg.tags
+	}
+	assert.Equal(t, " ", tags["space"].Config.PairSeparator)
+	assert.Equal(t, "", tags["space"].MapName)
+	assert.Equal(t, ",", tags["comma"].Config.PairSeparator)
+	assert.Equal(t, "csv", tags["comma"].MapName)
+	assert.Equal(t, map[string]string{"a": "fa", "b": "fb"}, tags["comma"].Map)
+	assert.False(t, tags["plain"].IsSet())
+	if assert.Len(t, warnings.Message, 1) {
+		assert.Contains(t, warnings.Message[0].Text, "ignored tagvalmap='csv' in MESSAGE without tagval")
+	}
+
+	dev.Messages = []*model.Message{
+		{ID1: "bad", ID2: "bad", TagVal: "true", TagValMap: "tsv", Content: "a=<fa>"},
+	}
+	_, err = New(dev, config.Config{}, &warnings)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "unknown tagvalmap='tsv'")
+	}
+}
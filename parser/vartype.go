@@ -0,0 +1,82 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/adriansr/nwdevice2filebeat/model"
+)
+
+// VarType is a VARTYPE definition. Any value captured into a field with the
+// same name as the VarType must match its regular expression in full.
+type VarType struct {
+	SourceContext
+	Name       string
+	Regex      string
+	IgnoreCase bool
+}
+
+// Pattern returns the regular expression anchored at both ends, without
+// flags. Outputs that don't support inline flags use it along IgnoreCase.
+func (vt VarType) Pattern() string {
+	return "^(?:" + vt.Regex + ")$"
+}
+
+// Expr returns the expression used to validate a captured value. It is
+// anchored at both ends and carries the case-insensitive flag when needed.
+func (vt VarType) Expr() string {
+	expr := vt.Pattern()
+	if vt.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	return expr
+}
+
+func (p *Parser) processVarTypes(input []*model.VarType) (output []VarType, byName map[string]*VarType, err error) {
+	byName = make(map[string]*VarType, len(input))
+	output = make([]VarType, len(input))
+	for idx, xml := range input {
+		vt, err := newVarType(xml)
+		if err != nil {
+			return output, byName, errors.Wrapf(err, "error parsing VARTYPE at %s", xml.Pos())
+		}
+		if prev := byName[vt.Name]; prev != nil {
+			return output, byName, errors.Errorf("duplicated VARTYPE name at %s (previous definition at %s)",
+				xml.Pos(), prev.Source())
+		}
+		output[idx] = vt
+		byName[vt.Name] = &output[idx]
+	}
+	return output, byName, nil
+}
+
+func newVarType(xml *model.VarType) (vt VarType, err error) {
+	vt = VarType{
+		SourceContext: SourceContext(xml.Pos()),
+		Name:          strings.TrimSpace(xml.Name),
+		Regex:         xml.Regex,
+	}
+	if vt.Name == "" {
+		return vt, errors.New("name attribute is empty")
+	}
+	if vt.Regex == "" {
+		return vt, errors.New("regex attribute is empty")
+	}
+	switch strings.ToLower(strings.TrimSpace(xml.IgnoreCase)) {
+	case "", "false", "0":
+	case "true", "1":
+		vt.IgnoreCase = true
+	default:
+		return vt, errors.Errorf("invalid ignorecase value '%s'", xml.IgnoreCase)
+	}
+	if _, err = regexp.Compile(vt.Expr()); err != nil {
+		return vt, errors.Wrapf(err, "cannot compile regex '%s'", vt.Regex)
+	}
+	return vt, nil
+}
@@ -0,0 +1,91 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adriansr/nwdevice2filebeat/model"
+)
+
+func TestNewVarType(t *testing.T) {
+	for _, testCase := range []struct {
+		title    string
+		input    model.VarType
+		expected VarType
+		pattern  string
+		expr     string
+		err      bool
+	}{
+		{
+			title: "case sensitive",
+			input: model.VarType{
+				Name:  "day",
+				Regex: "3[01]|2[0-9]|1[0-9]|0[1-9]|[1-9]",
+			},
+			expected: VarType{
+				Name:  "day",
+				Regex: "3[01]|2[0-9]|1[0-9]|0[1-9]|[1-9]",
+			},
+			pattern: "^(?:3[01]|2[0-9]|1[0-9]|0[1-9]|[1-9])$",
+			expr:    "^(?:3[01]|2[0-9]|1[0-9]|0[1-9]|[1-9])$",
+		},
+		{
+			title: "ignore case",
+			input: model.VarType{
+				Name:       "month",
+				Regex:      "Jan|Feb",
+				IgnoreCase: "true",
+			},
+			expected: VarType{
+				Name:       "month",
+				Regex:      "Jan|Feb",
+				IgnoreCase: true,
+			},
+			pattern: "^(?:Jan|Feb)$",
+			expr:    "(?i)^(?:Jan|Feb)$",
+		},
+		{
+			title: "bad ignorecase",
+			input: model.VarType{
+				Name:       "month",
+				Regex:      "Jan|Feb",
+				IgnoreCase: "yes",
+			},
+			err: true,
+		},
+		{
+			title: "bad regex",
+			input: model.VarType{
+				Name:  "time",
+				Regex: `\d{2}:(\d{2}`,
+			},
+			err: true,
+		},
+		{
+			title: "empty name",
+			input: model.VarType{
+				Regex: `\d+`,
+			},
+			err: true,
+		},
+	} {
+		t.Run(testCase.title, func(t *testing.T) {
+			result, err := newVarType(&testCase.input)
+			if testCase.err {
+				assert.Error(t, err)
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, testCase.expected, result)
+			assert.Equal(t, testCase.pattern, result.Pattern())
+			assert.Equal(t, testCase.expr, result.Expr())
+		})
+	}
+}
@@ -37,6 +37,7 @@ func walk(ref *Operation, visitor WalkFn) WalkAction {
 				return act
 			}
 		}
+		act = walkDefault(ref, visitor, walk)
 	case WalkSkip:
 		act = WalkContinue
 
@@ -51,6 +52,9 @@ func walkPostOrder(ref *Operation, visitor WalkFn) WalkAction {
 			return act
 		}
 	}
+	if act := walkDefault(ref, visitor, walkPostOrder); act == WalkCancel {
+		return act
+	}
 	act, repl := visitor(*ref)
 	if act == WalkReplace {
 		*ref = repl
@@ -58,3 +62,15 @@ func walkPostOrder(ref *Operation, visitor WalkFn) WalkAction {
 	}
 	return act
 }
+
+// walkDefault walks the catch-all message of a MsgIdSelect, which is not
+// one of its children.
+func walkDefault(ref *Operation, visitor WalkFn, walker func(*Operation, WalkFn) WalkAction) WalkAction {
+	sel, ok := (*ref).(MsgIdSelect)
+	if !ok || sel.Default == nil {
+		return WalkContinue
+	}
+	act := walker(&sel.Default, visitor)
+	*ref = sel
+	return act
+}
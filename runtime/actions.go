@@ -20,6 +20,14 @@ type Context struct {
 	Warnings util.Warnings
 	Logger   util.VerbosityLogger
 	Config   *config.Config
+	// Trace, when set, records the steps taken to process the message.
+	Trace *Trace
+	// HeaderID and MessageID are the IDs of the HEADER and MESSAGE that
+	// matched the log.
+	HeaderID  string
+	MessageID string
+	// lastMatch is the ID of the last pattern that matched.
+	lastMatch string
 }
 
 type Node interface {
@@ -105,19 +113,49 @@ func (r RemoveFields) Run(ctx *Context) error {
 	return nil
 }
 
-type MapSelect map[string]Node
+type MapSelect struct {
+	Map map[string]Node
+	// Default is the catch-all message for unmapped messageids, if any.
+	Default Node
+}
 
 var ErrMessageIDNotFound = errors.New("messageid not found")
 var ErrMessageIDNotMapped = errors.New("no mapping for messageid")
 
+// Flag set in FlagsField when an event is parsed by the catch-all message,
+// same as the javascript pipeline.
+const (
+	FlagsField           = "log.flags"
+	FlagUnknownMessageID = "unknown_messageid"
+)
+
 func (m MapSelect) Run(ctx *Context) error {
+	ctx.HeaderID = ctx.lastMatch
 	mID, err := ctx.Fields.Get("messageid")
 	if err != nil {
+		ctx.Trace.selectMessages("", SelectNoMessageID)
 		return ErrMessageIDNotFound
 	}
-	if next, found := m[mID]; found {
-		return next.Run(ctx)
+	if next, found := m.Map[mID]; found {
+		ctx.Trace.selectMessages(mID, SelectMapped)
+		if err := next.Run(ctx); err != nil {
+			return err
+		}
+		ctx.MessageID = ctx.lastMatch
+		return nil
+	}
+	if m.Default != nil {
+		ctx.Trace.selectMessages(mID, SelectCatchAll)
+		if err := m.Default.Run(ctx); err != nil {
+			return err
+		}
+		ctx.MessageID = ctx.lastMatch
+		if flags, _ := ctx.Fields.Get(FlagsField); flags == "" {
+			ctx.Fields.Put(FlagsField, FlagUnknownMessageID)
+		}
+		return nil
 	}
+	ctx.Trace.selectMessages(mID, SelectNotMapped)
 	return ErrMessageIDNotMapped
 }
 
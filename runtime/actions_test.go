@@ -0,0 +1,73 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapSelect(t *testing.T) {
+	known := &SetConstant{Field: "known", Value: "yes"}
+	catchAll := CopyMsg("event_description")
+	for _, test := range []struct {
+		title    string
+		sel      MapSelect
+		fields   Fields
+		expected Fields
+		err      error
+	}{
+		{
+			title:    "mapped",
+			sel:      MapSelect{Map: map[string]Node{"A": known}, Default: catchAll},
+			fields:   Fields{"messageid": "A"},
+			expected: Fields{"messageid": "A", "known": "yes"},
+		},
+		{
+			title:  "catch-all",
+			sel:    MapSelect{Map: map[string]Node{"A": known}, Default: catchAll},
+			fields: Fields{"messageid": "B"},
+			expected: Fields{
+				"messageid":         "B",
+				"event_description": "payload",
+				FlagsField:          FlagUnknownMessageID,
+			},
+		},
+		{
+			title:  "catch-all keeps flags",
+			sel:    MapSelect{Map: map[string]Node{"A": known}, Default: catchAll},
+			fields: Fields{"messageid": "B", FlagsField: "dissect_parsing_error"},
+			expected: Fields{
+				"messageid":         "B",
+				"event_description": "payload",
+				FlagsField:          "dissect_parsing_error",
+			},
+		},
+		{
+			title:    "no catch-all",
+			sel:      MapSelect{Map: map[string]Node{"A": known}},
+			fields:   Fields{"messageid": "B"},
+			expected: Fields{"messageid": "B"},
+			err:      ErrMessageIDNotMapped,
+		},
+		{
+			title:    "no messageid",
+			sel:      MapSelect{Map: map[string]Node{"A": known}, Default: catchAll},
+			fields:   Fields{},
+			expected: Fields{},
+			err:      ErrMessageIDNotFound,
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			ctx := Context{
+				Message: []byte("payload"),
+				Fields:  test.fields,
+			}
+			assert.Equal(t, test.err, test.sel.Run(&ctx))
+			assert.Equal(t, test.expected, ctx.Fields)
+		})
+	}
+}
@@ -0,0 +1,34 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import "github.com/adriansr/nwdevice2filebeat/ecs"
+
+// mapEventCategory is map_event_category in the javascript library. It
+// populates the ECS categorization fields from the eventcategory set by the
+// matched MESSAGE. An outcome already mapped from the log (ec_outcome) takes
+// precedence.
+func mapEventCategory(doc Document, fields Fields, table ecs.Categories) {
+	code, err := fields.Get("eventcategory")
+	if err != nil {
+		return
+	}
+	cat, found := table.Lookup(code)
+	if !found {
+		return
+	}
+	if cat.Kind != "" {
+		doc.Put("event.kind", cat.Kind)
+	}
+	if len(cat.Category) > 0 {
+		doc.Put("event.category", cat.Category)
+	}
+	if len(cat.Type) > 0 {
+		doc.Put("event.type", cat.Type)
+	}
+	if _, found := doc.Get("event.outcome"); !found && cat.Outcome != "" {
+		doc.Put("event.outcome", cat.Outcome)
+	}
+}
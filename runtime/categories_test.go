@@ -0,0 +1,87 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adriansr/nwdevice2filebeat/ecs"
+)
+
+func Test_mapEventCategory(t *testing.T) {
+	table := ecs.Categories{
+		"1401030000": {
+			Code:     "1401030000",
+			Kind:     "event",
+			Category: []string{"authentication"},
+			Type:     []string{"start"},
+			Outcome:  "failure",
+		},
+		"1402000000": {
+			Code:     "1402000000",
+			Kind:     "event",
+			Category: []string{"iam"},
+			Type:     []string{"user", "change"},
+		},
+	}
+	for _, test := range []struct {
+		title    string
+		fields   Fields
+		doc      Document
+		expected Document
+	}{
+		{
+			title:    "no eventcategory",
+			fields:   Fields{"msg": "x"},
+			expected: Document{},
+		},
+		{
+			title:  "exact",
+			fields: Fields{"eventcategory": "1401030000"},
+			expected: Document{"event": Document{
+				"kind":     "event",
+				"category": []string{"authentication"},
+				"type":     []string{"start"},
+				"outcome":  "failure",
+			}},
+		},
+		{
+			title:  "parent",
+			fields: Fields{"eventcategory": "1402020200"},
+			expected: Document{"event": Document{
+				"kind":     "event",
+				"category": []string{"iam"},
+				"type":     []string{"user", "change"},
+			}},
+		},
+		{
+			title:  "keep outcome",
+			fields: Fields{"eventcategory": "1401030000", "ec_outcome": "Success"},
+			doc:    Document{"event": Document{"outcome": "success"}},
+			expected: Document{"event": Document{
+				"kind":     "event",
+				"category": []string{"authentication"},
+				"type":     []string{"start"},
+				"outcome":  "success",
+			}},
+		},
+		{
+			title:    "unmapped",
+			fields:   Fields{"eventcategory": "1901000000"},
+			expected: Document{},
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			doc := test.doc
+			if doc == nil {
+				doc = make(Document)
+			}
+			mapEventCategory(doc, test.fields, table)
+			assert.Equal(t, test.expected, doc)
+		})
+	}
+}
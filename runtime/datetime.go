@@ -81,7 +81,7 @@ func (d dateTime) tryConvert(str string, ctx *Context) bool {
 		if err == nil {
 			log.Printf("EVNTTIME succeeded str=%s format=%s result=%s",
 				str, format, date.String())
-			ctx.Fields.Put(d.target, date.String())
+			ctx.Fields.Set(d.target, date)
 			return true
 		}
 	}
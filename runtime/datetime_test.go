@@ -6,6 +6,7 @@ package runtime
 
 import (
 	"testing"
+	"time"
 
 	"github.com/adriansr/nwdevice2filebeat/parser"
 	"github.com/stretchr/testify/assert"
@@ -74,3 +75,28 @@ func Test_duration_Run(t *testing.T) {
 		})
 	}
 }
+
+func Test_dateTime_Run(t *testing.T) {
+	d, err := newDateTime(parser.DateTime{
+		Target: "event_time",
+		Fields: []string{"hdate", "htime"},
+		Formats: []DTFormat{
+			{S('W'), parser.Constant("-"), S('M'), parser.Constant("-"), S('D'), S('Z')},
+		},
+		IsUTC: true,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := Context{
+		Fields: Fields{"hdate": "2020-03-10", "htime": "14:27:03"},
+	}
+	if err = d.Run(&ctx); err != nil {
+		t.Fatal(err)
+	}
+	// The result is stored as a timestamp.
+	assert.Equal(t, time.Date(2020, 3, 10, 14, 27, 3, 0, time.UTC), ctx.Fields["event_time"])
+	str, err := ctx.Fields.Get("event_time")
+	assert.NoError(t, err)
+	assert.Equal(t, "2020-03-10 14:27:03 +0000 UTC", str)
+}
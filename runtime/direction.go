@@ -0,0 +1,121 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"net"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// Values returned by DIRCHK, which are stored in the inout field.
+const (
+	dirChkInbound  = "0"
+	dirChkOutbound = "1"
+)
+
+// Values for network.direction.
+const (
+	directionInbound  = "inbound"
+	directionOutbound = "outbound"
+	directionInternal = "internal"
+	directionExternal = "external"
+)
+
+// Ports below this one are considered service ports when trying to guess
+// which side of a connection is the client.
+const maxServicePort = 1024
+
+var errDirChkArguments = errors.New("expected DIRCHK(addr) or DIRCHK($IN|$OUT,saddr,daddr[,sport,dport])")
+
+// networkDirection implements DIRCHK. The different forms of this function are:
+//
+// DIRCHK(saddr) => '1' (outbound) if saddr is local, '0' (inbound) otherwise.
+//
+// DIRCHK($IN|$OUT,saddr,daddr) => '1' if traffic goes from a local address to
+// a remote one, '0' for the opposite. When both or none of the addresses are
+// local, '0' is returned for $IN and '1' for $OUT.
+//
+// DIRCHK($IN|$OUT,saddr,daddr,sport,dport) is the same as above, but the
+// source and destination are swapped when the ports show that saddr is the
+// server side of the connection.
+//
+// It also sets the direction field, unless already set, from which
+// network.direction is populated.
+func networkDirection(args []string, ctx *Context) (string, error) {
+	var result, direction string
+	switch len(args) {
+	case 1:
+		local, err := isLocalAddress(args[0], ctx)
+		if err != nil {
+			return dirChkInbound, err
+		}
+		result, direction = dirChkInbound, directionInbound
+		if local {
+			result, direction = dirChkOutbound, directionOutbound
+		}
+
+	case 3, 5:
+		switch args[0] {
+		case "$IN":
+			result = dirChkInbound
+		case "$OUT":
+			result = dirChkOutbound
+		default:
+			return dirChkInbound, errDirChkArguments
+		}
+		src, dst := args[1], args[2]
+		if len(args) == 5 && isServerPort(args[3]) && !isServerPort(args[4]) {
+			src, dst = dst, src
+		}
+		srcLocal, err := isLocalAddress(src, ctx)
+		if err != nil {
+			return result, err
+		}
+		dstLocal, err := isLocalAddress(dst, ctx)
+		if err != nil {
+			return result, err
+		}
+		switch {
+		case srcLocal && dstLocal:
+			direction = directionInternal
+		case !srcLocal && !dstLocal:
+			direction = directionExternal
+		case srcLocal:
+			result, direction = dirChkOutbound, directionOutbound
+		default:
+			result, direction = dirChkInbound, directionInbound
+		}
+
+	default:
+		return dirChkInbound, errDirChkArguments
+	}
+	if _, found := ctx.Fields["direction"]; !found {
+		ctx.Fields.Put("direction", direction)
+	}
+	return result, nil
+}
+
+func isLocalAddress(addr string, ctx *Context) (bool, error) {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false, errors.Errorf("failed to parse '%s' as IP for network direction check.", addr)
+	}
+	// IPNet.Contains handles IPv4-mapped IPv6 addresses and networks as IPv4,
+	// and so does is_local_address in the javascript library.
+	// TODO: Implement this better than O(n)
+	for _, network := range ctx.Config.Runtime.LocalNetworks {
+		if network.Contains(ip) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func isServerPort(port string) bool {
+	num, err := strconv.Atoi(port)
+	return err == nil && num > 0 && num < maxServicePort
+}
@@ -0,0 +1,189 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adriansr/nwdevice2filebeat/config"
+	"github.com/adriansr/nwdevice2filebeat/model"
+	"github.com/adriansr/nwdevice2filebeat/parser"
+	"github.com/adriansr/nwdevice2filebeat/util"
+)
+
+func Test_networkDirection(t *testing.T) {
+	var cfg config.Config
+	var err error
+	cfg.Runtime.LocalNetworks, err = config.ParseNetworks([]string{"10.0.0.0/8", "fd00::/8", "::ffff:192.168.0.0/112"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, test := range []struct {
+		title     string
+		args      []string
+		fields    Fields
+		result    string
+		direction string
+		err       bool
+	}{
+		// The single-argument form returns '1' for local addresses, as in the
+		// javascript pipeline. Before the other forms were implemented it
+		// returned '0'.
+		{
+			title:     "single local",
+			args:      []string{"10.1.2.3"},
+			result:    "1",
+			direction: "outbound",
+		},
+		{
+			title:     "single remote",
+			args:      []string{"8.8.8.8"},
+			result:    "0",
+			direction: "inbound",
+		},
+		{
+			title:     "single ipv6",
+			args:      []string{"fd12::1"},
+			result:    "1",
+			direction: "outbound",
+		},
+		{
+			title:     "single ipv4-mapped",
+			args:      []string{"::ffff:10.1.2.3"},
+			result:    "1",
+			direction: "outbound",
+		},
+		{
+			title:     "single in ipv4-mapped network",
+			args:      []string{"192.168.1.1"},
+			result:    "1",
+			direction: "outbound",
+		},
+		{
+			title:     "single outside ipv4-mapped network",
+			args:      []string{"::ffff:192.169.1.1"},
+			result:    "0",
+			direction: "inbound",
+		},
+		{
+			title:     "outbound",
+			args:      []string{"$IN", "10.1.2.3", "8.8.8.8"},
+			result:    "1",
+			direction: "outbound",
+		},
+		{
+			title:     "inbound",
+			args:      []string{"$OUT", "8.8.8.8", "10.1.2.3"},
+			result:    "0",
+			direction: "inbound",
+		},
+		{
+			title:     "internal defaults to OUT",
+			args:      []string{"$OUT", "10.1.2.3", "10.3.2.1"},
+			result:    "1",
+			direction: "internal",
+		},
+		{
+			title:     "external defaults to IN",
+			args:      []string{"$IN", "8.8.8.8", "8.8.4.4"},
+			result:    "0",
+			direction: "external",
+		},
+		{
+			title:     "client to server",
+			args:      []string{"$OUT", "10.1.2.3", "8.8.8.8", "51234", "53"},
+			result:    "1",
+			direction: "outbound",
+		},
+		{
+			title:     "server to client",
+			args:      []string{"$OUT", "8.8.8.8", "10.1.2.3", "53", "51234"},
+			result:    "1",
+			direction: "outbound",
+		},
+		{
+			title:     "keep direction",
+			args:      []string{"10.1.2.3"},
+			fields:    Fields{"direction": "egress"},
+			result:    "1",
+			direction: "egress",
+		},
+		{
+			title: "bad address",
+			args:  []string{"$OUT", "10.1.2.3", "nope"},
+			err:   true,
+		},
+		{
+			title: "bad default",
+			args:  []string{"$UP", "10.1.2.3", "8.8.8.8"},
+			err:   true,
+		},
+		{
+			title: "bad arguments",
+			args:  []string{"$OUT", "10.1.2.3"},
+			err:   true,
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			ctx := Context{Fields: test.fields, Config: &cfg}
+			if ctx.Fields == nil {
+				ctx.Fields = make(Fields)
+			}
+			result, err := networkDirection(test.args, &ctx)
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.result, result)
+			assert.Equal(t, test.direction, ctx.Fields["direction"])
+		})
+	}
+}
+
+// Pins the inout values set by DIRCHK(saddr) in a MESSAGE.
+func TestProcessor_dirChkSingleArgument(t *testing.T) {
+	dev := model.Device{
+		Headers: []*model.Header{
+			{ID1: "0001", ID2: "0001", Content: "<messageid>: <!payload>"},
+		},
+		Messages: []*model.Message{
+			{
+				ID1:       "conn",
+				ID2:       "conn",
+				Content:   "from <saddr>",
+				Functions: "<@inout:*DIRCHK(saddr)>",
+			},
+		},
+	}
+	var cfg config.Config
+	var err error
+	cfg.Runtime.LocalNetworks, err = config.ParseNetworks([]string{"10.0.0.0/8"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	warnings := util.NewWarnings(10)
+	p, err := parser.New(dev, cfg, &warnings)
+	if !assert.NoError(t, err) {
+		return
+	}
+	proc, err := New(&p, &warnings, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, test := range []struct {
+		log, inout, direction string
+	}{
+		{log: "conn: from 10.1.2.3", inout: "1", direction: "outbound"},
+		{log: "conn: from 8.8.8.8", inout: "0", direction: "inbound"},
+	} {
+		evt := proc.ProcessEvent([]byte(test.log))
+		assert.Empty(t, evt.Errors, test.log)
+		assert.Equal(t, test.inout, evt.Fields["inout"], test.log)
+		assert.Equal(t, test.direction, evt.Fields["direction"], test.log)
+	}
+}
@@ -6,7 +6,6 @@ package runtime
 
 import (
 	"bytes"
-	"net"
 	"strconv"
 	"strings"
 
@@ -18,7 +17,7 @@ type FunctionImpl func([]string, *Context) (string, error)
 
 var knownFunctions = map[string]FunctionImpl{
 	"CALC":       calc,
-	"CNVTDOMAIN": notimpl,
+	"CNVTDOMAIN": convertDomain,
 	"DIRCHK":     networkDirection,
 	"RMQ":        removeQuotes,
 	"STRCAT":     strcat,
@@ -139,10 +138,7 @@ func notimpl([]string, *Context) (string, error) {
 
 var quoteChars = []byte("\"'`")
 
-var (
-	errOneArgument     = errors.New("function requires exactly one argument")
-	errDirChkArguments = errors.New("only single-argument form is supported")
-)
+var errOneArgument = errors.New("function requires exactly one argument")
 
 func removeQuotes(args []string, _ *Context) (string, error) {
 	// RMQ always has one argument.
@@ -160,31 +156,45 @@ func removeQuotes(args []string, _ *Context) (string, error) {
 	return str, nil
 }
 
-const (
-	dirChkInside  = "1"
-	dirChkOutside = "0"
-)
-
-func networkDirection(args []string, ctx *Context) (string, error) {
-	// The different forms of this function are:
-	// DIRCHK(saddr) => '1' if addr is Inside '0' if Outside.
-	// DIRCHK($IN/$OUT,saddr,daddr)
-	// DIRCHK($IN/$OUT,saddr,daddr,sport,dport)
-	//
-	// Don't know what the multiargument ones mean exactly and what is a proper
-	// result. These are only used in Netscreen log parser.
+// convertDomain implements CNVTDOMAIN, which converts a domain name in DNS
+// wire-format, where each label is preceded by its length, to dotted form:
+// (3)www(7)example(3)com(0) => www.example.com
+// Values that are not in wire-format are returned unchanged.
+func convertDomain(args []string, _ *Context) (string, error) {
 	if len(args) != 1 {
-		return dirChkOutside, errDirChkArguments
+		return "", errOneArgument
 	}
-	ip := net.ParseIP(args[0])
-	if ip == nil {
-		return dirChkOutside, errors.Errorf("failed to parse '%s' as IP for network direction check.", args[0])
+	str := strings.TrimSpace(args[0])
+	if len(str) == 0 || str[0] != '(' {
+		return str, nil
 	}
-	// TODO: Implement this better than O(n)
-	for _, net := range ctx.Config.Runtime.LocalNetworks {
-		if net.Contains(ip) {
-			return dirChkOutside, nil
+	var labels []string
+	for pos := 0; pos < len(str); {
+		if str[pos] != '(' {
+			return "", errors.Errorf("expected label length at offset %d", pos)
+		}
+		end := strings.IndexByte(str[pos:], ')')
+		if end == -1 {
+			return "", errors.Errorf("unterminated label length at offset %d", pos)
+		}
+		// Only digits, as strconv.Atoi also accepts a leading sign.
+		digits := str[pos+1 : pos+end]
+		n, err := strconv.Atoi(digits)
+		if err != nil || strings.TrimLeft(digits, "0123456789") != "" {
+			return "", errors.Errorf("invalid label length at offset %d", pos)
+		}
+		pos += end + 1
+		if n == 0 {
+			if pos != len(str) {
+				return "", errors.Errorf("unexpected data after domain terminator at offset %d", pos)
+			}
+			break
+		}
+		if pos+n > len(str) {
+			return "", errors.Errorf("label at offset %d exceeds input length", pos)
 		}
+		labels = append(labels, str[pos:pos+n])
+		pos += n
 	}
-	return dirChkInside, nil
+	return strings.Join(labels, "."), nil
 }
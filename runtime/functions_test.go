@@ -0,0 +1,47 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_convertDomain(t *testing.T) {
+	for _, test := range []struct {
+		input    string
+		expected string
+		err      bool
+	}{
+		{input: "(3)www(7)example(3)com(0)", expected: "www.example.com"},
+		{input: "(3)www(7)example(3)com", expected: "www.example.com"},
+		{input: " (2)ab(0) ", expected: "ab"},
+		{input: "(4)a(b)(3)net(0)", expected: "a(b).net"},
+		{input: "(10)subdomains(2)co(2)uk(0)", expected: "subdomains.co.uk"},
+		{input: "(0)", expected: ""},
+		{input: "", expected: ""},
+		{input: "www.example.com", expected: "www.example.com"},
+		{input: "(3)www(10)example", err: true},
+		{input: "(3)wwwx(3)com", err: true},
+		{input: "(a)www", err: true},
+		{input: "(+3)www", err: true},
+		{input: "(-0)", err: true},
+		{input: "(3www", err: true},
+		{input: "(3)www(0)com", err: true},
+	} {
+		t.Run(test.input, func(t *testing.T) {
+			result, err := convertDomain([]string{test.input}, nil)
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+	_, err := convertDomain([]string{"a", "b"}, nil)
+	assert.Equal(t, errOneArgument, err)
+}
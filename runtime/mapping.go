@@ -0,0 +1,271 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adriansr/nwdevice2filebeat/ecs"
+)
+
+// Document is an event with nested fields, as produced by the generated
+// pipeline.
+type Document map[string]interface{}
+
+// Put stores a value in a dotted path, creating the intermediate objects.
+func (d Document) Put(path string, value interface{}) {
+	keys := strings.Split(path, ".")
+	obj := d
+	for _, key := range keys[:len(keys)-1] {
+		child, ok := obj[key].(Document)
+		if !ok {
+			child = make(Document)
+			obj[key] = child
+		}
+		obj = child
+	}
+	obj[keys[len(keys)-1]] = value
+}
+
+// Get returns the value at a dotted path.
+func (d Document) Get(path string) (interface{}, bool) {
+	keys := strings.Split(path, ".")
+	obj := d
+	for _, key := range keys[:len(keys)-1] {
+		child, ok := obj[key].(Document)
+		if !ok {
+			return nil, false
+		}
+		obj = child
+	}
+	value, found := obj[keys[len(keys)-1]]
+	return value, found
+}
+
+// Map is the mapping stage, which runs after convert. It populates an ECS
+// document from the NetWitness fields the same way populate_fields does in
+// the javascript library: ECS fields and categorization, rsa.* fields when
+// enabled, and the raw fields under rsa.raw when requested.
+func (p *Processor) Map(fields Fields) Document {
+	doc := make(Document)
+	if flags, found := fields[FlagsField]; found {
+		doc.Put(FlagsField, flags)
+	}
+	mappings := p.cfg.FieldMappings
+	populate(doc, fields, mappings.ECS)
+	mapSeverity(doc)
+	mapEventCategory(doc, fields, p.cfg.EventCategories)
+	if p.cfg.Runtime.MapRSA {
+		populate(doc, fields, mappings.RSA)
+	}
+	if p.cfg.Runtime.KeepRaw {
+		raw := make(Fields, len(fields))
+		for k, v := range fields {
+			raw[k] = v
+		}
+		doc.Put("rsa.raw", raw)
+	}
+	return doc
+}
+
+// slot holds the value for a target field while fields are being merged.
+type slot struct {
+	value interface{}
+	prio  int
+}
+
+// populate is do_populate in the javascript library. Fields are processed in
+// order, so that values appended to lists are in a predictable order.
+func populate(doc Document, fields Fields, table ecs.FieldMappings) {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		if _, found := table[key]; found && fields[key] != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	result := make(map[string]*slot)
+	for _, key := range keys {
+		mapping := table[key]
+		value, ok := convertValue(mapping.Convert, fields[key])
+		if !ok {
+			continue
+		}
+		for _, tgt := range mapping.To {
+			setTarget(result, tgt, value)
+		}
+	}
+	for field, s := range result {
+		doc.Put(field, s.value)
+	}
+}
+
+var validOutcomes = map[string]bool{
+	"failure": true,
+	"success": true,
+	"unknown": true,
+}
+
+func setTarget(result map[string]*slot, tgt ecs.Target, value interface{}) {
+	current := result[tgt.Field]
+	switch tgt.Mode {
+	case ecs.ModeAppend:
+		if current == nil {
+			result[tgt.Field] = &slot{value: []interface{}{value}}
+			return
+		}
+		list := current.value.([]interface{})
+		for _, v := range list {
+			if v == value {
+				return
+			}
+		}
+		current.value = append(list, value)
+	case ecs.ModePrio:
+		if current == nil || tgt.Prio < current.prio {
+			result[tgt.Field] = &slot{value: value, prio: tgt.Prio}
+		}
+	case ecs.ModeECSOutcome:
+		str, _ := value.(string)
+		if str = strings.ToLower(str); !validOutcomes[str] {
+			str = "unknown"
+		}
+		if current == nil || current.value == "unknown" {
+			result[tgt.Field] = &slot{value: str}
+		}
+	default:
+		result[tgt.Field] = &slot{value: value}
+	}
+}
+
+// convertValue applies the conversions in the javascript library (to_long,
+// to_ip, ...). Returns false when the value can't be converted, in which case
+// the field is not mapped. Values that are not strings have already been
+// converted by the typing stage or by a function, and are kept as is.
+func convertValue(conv ecs.Conversion, value interface{}) (interface{}, bool) {
+	str, ok := value.(string)
+	if !ok {
+		return value, true
+	}
+	switch conv {
+	case ecs.ConvertLong:
+		return toLong(str)
+	case ecs.ConvertDouble:
+		return toDouble(str)
+	case ecs.ConvertIP:
+		return toIP(str)
+	case ecs.ConvertDate:
+		return toDate(str)
+	case ecs.ConvertMAC:
+		// As in to_mac, any value is accepted.
+		return str, true
+	default:
+		return str, true
+	}
+}
+
+// Largest integer that can be safely represented in javascript.
+const maxSafeInt = 1<<53 - 1
+
+// toLong behaves like parseInt: it uses the leading integer in the value and
+// ignores the rest.
+func toLong(value string) (interface{}, bool) {
+	value = strings.TrimLeft(value, " \t\r\n")
+	end := 0
+	if end < len(value) && (value[end] == '-' || value[end] == '+') {
+		end++
+	}
+	digits := end
+	for end < len(value) && value[end] >= '0' && value[end] <= '9' {
+		end++
+	}
+	if end == digits {
+		return nil, false
+	}
+	num, err := strconv.ParseInt(value[:end], 10, 64)
+	if err != nil || num > maxSafeInt || num < -maxSafeInt {
+		return nil, false
+	}
+	return num, true
+}
+
+// toDouble behaves like parseFloat, using the longest prefix of the value
+// that is a number.
+func toDouble(value string) (interface{}, bool) {
+	value = strings.TrimLeft(value, " \t\r\n")
+	for end := len(value); end > 0; end-- {
+		if num, err := strconv.ParseFloat(value[:end], 64); err == nil {
+			if math.IsNaN(num) || math.IsInf(num, 0) {
+				return nil, false
+			}
+			return num, true
+		}
+	}
+	return nil, false
+}
+
+// toIP validates an IP address. As in to_ip, square brackets and zone are
+// removed from IPv6 addresses.
+func toIP(value string) (interface{}, bool) {
+	if strings.IndexByte(value, ':') == -1 {
+		if !isIPv4(value) {
+			return nil, false
+		}
+		return value, true
+	}
+	if end := strings.IndexByte(value, ']'); end != -1 {
+		if value[0] != '[' {
+			return nil, false
+		}
+		value = value[1:end]
+	}
+	if zone := strings.IndexByte(value, '%'); zone != -1 {
+		value = value[:zone]
+	}
+	if net.ParseIP(value) == nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// isIPv4 accepts four decimal numbers in the 0-255 range separated by dots.
+// Unlike net.ParseIP, leading zeros are allowed.
+func isIPv4(value string) bool {
+	parts := strings.Split(value, ".")
+	if len(parts) != 4 {
+		return false
+	}
+	for _, part := range parts {
+		if part == "" || len(part) > 3 {
+			return false
+		}
+		num, err := strconv.Atoi(part)
+		if err != nil || num < 0 || num > 255 || part[0] == '+' || part[0] == '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// Formats accepted by toDate. The first is the format used by EVNTTIME to
+// store dates.
+var dateLayouts = []string{
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+	time.RFC3339Nano,
+}
+
+func toDate(value string) (interface{}, bool) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return nil, false
+}
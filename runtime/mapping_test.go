@@ -0,0 +1,193 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adriansr/nwdevice2filebeat/config"
+	"github.com/adriansr/nwdevice2filebeat/ecs"
+)
+
+func TestDocument(t *testing.T) {
+	doc := make(Document)
+	doc.Put("message", "hello")
+	doc.Put("source.ip", "10.0.0.1")
+	doc.Put("source.port", int64(22))
+	assert.Equal(t, Document{
+		"message": "hello",
+		"source": Document{
+			"ip":   "10.0.0.1",
+			"port": int64(22),
+		},
+	}, doc)
+	value, found := doc.Get("source.port")
+	assert.True(t, found)
+	assert.Equal(t, int64(22), value)
+	_, found = doc.Get("source.bytes")
+	assert.False(t, found)
+	_, found = doc.Get("message.text")
+	assert.False(t, found)
+}
+
+func Test_populate(t *testing.T) {
+	table := ecs.FieldMappings{
+		"msg":               {To: []ecs.Target{{Field: "message", Mode: ecs.ModePrio, Prio: 1}}},
+		"event_description": {To: []ecs.Target{{Field: "message", Mode: ecs.ModePrio}}},
+		"username": {To: []ecs.Target{
+			{Field: "user.name", Mode: ecs.ModeSet},
+			{Field: "related.user", Mode: ecs.ModeAppend},
+		}},
+		"dclass_counter1": {To: []ecs.Target{{Field: "related.user", Mode: ecs.ModeAppend}}},
+		"saddr": {Convert: ecs.ConvertIP, To: []ecs.Target{
+			{Field: "source.ip", Mode: ecs.ModeSet},
+		}},
+		"sport":       {Convert: ecs.ConvertLong, To: []ecs.Target{{Field: "source.port", Mode: ecs.ModeSet}}},
+		"result":      {To: []ecs.Target{{Field: "event.outcome", Mode: ecs.ModeECSOutcome}}},
+		"disposition": {To: []ecs.Target{{Field: "event.outcome", Mode: ecs.ModeECSOutcome}}},
+	}
+	for _, test := range []struct {
+		title    string
+		fields   Fields
+		expected Document
+	}{
+		{
+			title:    "unmapped",
+			fields:   Fields{"other": "x", "msg": ""},
+			expected: Document{},
+		},
+		{
+			title:  "prio",
+			fields: Fields{"msg": "a", "event_description": "b"},
+			expected: Document{
+				"message": "b",
+			},
+		},
+		{
+			title:  "append",
+			fields: Fields{"username": "root", "dclass_counter1": "root"},
+			expected: Document{
+				"user":    Document{"name": "root"},
+				"related": Document{"user": []interface{}{"root"}},
+			},
+		},
+		{
+			title:  "conversion",
+			fields: Fields{"saddr": "10.0.0.1", "sport": "22"},
+			expected: Document{
+				"source": Document{"ip": "10.0.0.1", "port": int64(22)},
+			},
+		},
+		{
+			title:    "invalid conversion",
+			fields:   Fields{"saddr": "localhost", "sport": "ssh"},
+			expected: Document{},
+		},
+		{
+			title:  "outcome",
+			fields: Fields{"disposition": "Blocked", "result": "SUCCESS"},
+			expected: Document{
+				"event": Document{"outcome": "success"},
+			},
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			doc := make(Document)
+			populate(doc, test.fields, table)
+			assert.Equal(t, test.expected, doc)
+		})
+	}
+}
+
+func TestProcessor_Map(t *testing.T) {
+	cfg := config.Config{
+		FieldMappings: ecs.Mappings{
+			ECS: ecs.FieldMappings{
+				"level":  {Convert: ecs.ConvertLong, To: []ecs.Target{{Field: "event.severity", Mode: ecs.ModeSet}}},
+				"result": {To: []ecs.Target{{Field: "event.outcome", Mode: ecs.ModeECSOutcome}}},
+			},
+			RSA: ecs.FieldMappings{
+				"level": {Convert: ecs.ConvertLong, To: []ecs.Target{{Field: "rsa.internal.level", Mode: ecs.ModeSet}}},
+			},
+		},
+		EventCategories: ecs.Categories{
+			"1401030000": {
+				Code:     "1401030000",
+				Kind:     "event",
+				Category: []string{"authentication"},
+				Type:     []string{"start"},
+				Outcome:  "failure",
+			},
+		},
+	}
+	fields := Fields{
+		"level":         "3",
+		"result":        "success",
+		"eventcategory": "1401030000",
+		FlagsField:      FlagUnknownMessageID,
+	}
+	p := Processor{cfg: &cfg}
+	assert.Equal(t, Document{
+		"event": Document{
+			"severity": int64(3),
+			"outcome":  "success",
+			"kind":     "event",
+			"category": []string{"authentication"},
+			"type":     []string{"start"},
+		},
+		"log": Document{
+			"flags": FlagUnknownMessageID,
+			"level": "error",
+		},
+	}, p.Map(fields))
+
+	cfg.Runtime.MapRSA = true
+	cfg.Runtime.KeepRaw = true
+	doc := p.Map(fields)
+	value, _ := doc.Get("rsa.internal.level")
+	assert.Equal(t, int64(3), value)
+	value, _ = doc.Get("rsa.raw")
+	assert.Equal(t, fields, value)
+}
+
+func Test_convertValue(t *testing.T) {
+	date := time.Date(2020, 3, 10, 14, 27, 3, 0, time.UTC)
+	for _, test := range []struct {
+		conv     ecs.Conversion
+		value    string
+		expected interface{}
+	}{
+		{conv: ecs.ConvertNone, value: "text", expected: "text"},
+		{conv: ecs.ConvertLong, value: "1234", expected: int64(1234)},
+		{conv: ecs.ConvertLong, value: " -12 bytes", expected: int64(-12)},
+		{conv: ecs.ConvertLong, value: "0x10", expected: int64(0)},
+		{conv: ecs.ConvertLong, value: "bytes"},
+		{conv: ecs.ConvertLong, value: "9007199254740993"},
+		{conv: ecs.ConvertDouble, value: "1.5", expected: 1.5},
+		{conv: ecs.ConvertDouble, value: "2.5ms", expected: 2.5},
+		{conv: ecs.ConvertDouble, value: "NaN"},
+		{conv: ecs.ConvertIP, value: "10.0.0.1", expected: "10.0.0.1"},
+		{conv: ecs.ConvertIP, value: "010.000.000.001", expected: "010.000.000.001"},
+		{conv: ecs.ConvertIP, value: "[fe80::1%eth0]", expected: "fe80::1"},
+		{conv: ecs.ConvertIP, value: "10.0.0.256"},
+		{conv: ecs.ConvertIP, value: "host:80"},
+		{conv: ecs.ConvertMAC, value: "00:50:56:c0:00:08", expected: "00:50:56:c0:00:08"},
+		{conv: ecs.ConvertMAC, value: "0050.56c0.0008", expected: "0050.56c0.0008"},
+		{conv: ecs.ConvertDate, value: "2020-03-10 14:27:03 +0000 UTC", expected: date},
+		{conv: ecs.ConvertDate, value: "2020-03-10T14:27:03Z", expected: date},
+		{conv: ecs.ConvertDate, value: "Mar 10 14:27:03"},
+	} {
+		t.Run(string(test.conv)+" "+test.value, func(t *testing.T) {
+			value, ok := convertValue(test.conv, test.value)
+			assert.Equal(t, test.expected != nil, ok)
+			if ok {
+				assert.Equal(t, test.expected, value)
+			}
+		})
+	}
+}
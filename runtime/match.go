@@ -6,6 +6,7 @@ package runtime
 
 import (
 	"bytes"
+	"regexp"
 	"strings"
 
 	"github.com/adriansr/nwdevice2filebeat/parser"
@@ -14,8 +15,14 @@ import (
 )
 
 type match struct {
+	id        string
 	pattern   [][]pattern
 	onSuccess []Node
+	// actions are the operations onSuccess was translated from, used to
+	// describe them in a Trace.
+	actions []parser.Operation
+	// varTypes are the VARTYPE expressions that captured fields must match.
+	varTypes map[string]*regexp.Regexp
 }
 
 func (m match) String() string {
@@ -234,6 +241,12 @@ type capture struct {
 type captures struct {
 	fields  []capture
 	payload int
+	// When the pattern doesn't match, failPos is the position where it
+	// failed and expected the constant needed there. If searched is set, the
+	// constant wasn't found anywhere after failPos.
+	failPos  int
+	expected []byte
+	searched bool
 }
 
 var ErrNoMatch = errors.New("pattern didn't match")
@@ -242,19 +255,32 @@ var ErrMultiplePayload = errors.New("multiple payloads captured")
 func (m *match) Run(ctx *Context) error {
 	ctx.Logger.Log(util.LogTrace, "-> run \"%s\"\n", m.String())
 	ctx.Logger.Log(util.LogTrace, " > msg ='%s'\n", ctx.Message)
+	ctx.Trace.beginMatch(m.id, m, ctx.Message)
 	fullCapture := captures{
 		payload: -1,
 	}
 	pos := 0
 	for _, chunk := range m.pattern {
 		var nextPos int
+		// The failure of the alternative that got further, for tracing.
+		failed := captures{failPos: -1}
 		for _, alt := range chunk {
 			var partial captures
 			ctx.Logger.Log(util.LogTrace, " ~> try: <<%s>>\n", ctx.Message[pos:])
-			if nextPos, partial = matchPattern(ctx.Message, pos, alt); nextPos != -1 {
+			nextPos, partial = matchPattern(ctx.Message, pos, alt)
+			if nextPos != -1 && !m.validate(ctx, partial) {
+				ctx.Logger.Log(util.LogTrace, " <~ rejected by VARTYPE <<%s>>\n", alt.String())
+				nextPos = -1
+				if pos > failed.failPos {
+					failed = captures{failPos: pos}
+				}
+				continue
+			}
+			if nextPos != -1 {
 				ctx.Logger.Log(util.LogTrace, " <~ matched <<%s>> payload: %d nexPos=%d\n", alt.String(), partial.payload, nextPos)
 				if partial.payload >= 0 {
 					if fullCapture.payload != -1 {
+						ctx.Trace.matchFailed(pos, nil, false, ErrMultiplePayload.Error())
 						return ErrMultiplePayload
 					}
 					fullCapture.payload = partial.payload
@@ -263,14 +289,24 @@ func (m *match) Run(ctx *Context) error {
 				break
 			} else {
 				ctx.Logger.Log(util.LogTrace, " <~ didn't match <<%s>> payload: %d\n", alt.String(), partial.payload)
+				if partial.failPos > failed.failPos {
+					failed = partial
+				}
 			}
 		}
 		if nextPos == -1 {
 			ctx.Logger.Log(util.LogTrace, "<- not matched\n")
+			if failed.expected == nil {
+				ctx.Trace.matchFailed(failed.failPos, nil, false, "rejected by VARTYPE")
+			} else {
+				ctx.Trace.matchFailed(failed.failPos, failed.expected, failed.searched, "")
+			}
 			return ErrNoMatch
 		}
 		pos = nextPos
 	}
+	ctx.Trace.matched()
+	ctx.lastMatch = m.id
 	if len(fullCapture.fields) > 0 {
 		for _, capture := range fullCapture.fields {
 			key, value := string(capture.field), string(ctx.Message[capture.start:capture.end])
@@ -282,10 +318,14 @@ func (m *match) Run(ctx *Context) error {
 	}
 	// Not sure about references to $MSG in function calls. Should it wait to
 	// update ctx.Message after functions applied?
-	for _, act := range m.onSuccess {
-		if err := act.Run(ctx); err != nil {
+	for idx, act := range m.onSuccess {
+		err := act.Run(ctx)
+		if err != nil {
 			ctx.Errors = append(ctx.Errors, err)
 		}
+		if idx < len(m.actions) {
+			ctx.Trace.action(m.actions[idx], err)
+		}
 	}
 	if fullCapture.payload >= 0 {
 		ctx.Message = ctx.Message[fullCapture.payload:]
@@ -296,13 +336,27 @@ func (m *match) Run(ctx *Context) error {
 	return nil
 }
 
+// validate checks that the captured values for fields that have a VARTYPE
+// match its expression.
+func (m *match) validate(ctx *Context, captured captures) bool {
+	if len(m.varTypes) == 0 {
+		return true
+	}
+	for _, capture := range captured.fields {
+		if re, found := m.varTypes[string(capture.field)]; found && !re.Match(ctx.Message[capture.start:capture.end]) {
+			return false
+		}
+	}
+	return true
+}
+
 func matchPattern(msg []byte, pos int, pattern pattern) (nextPos int, captured captures) {
 	captured.payload = -1
 	msgPos, msgLen := pos, len(msg)
 	itemIdx, numItems := 0, len(pattern)
 	if itemIdx < numItems && !pattern[itemIdx].isCapture {
 		if msgPos = skipConstant(msg, msgPos, pattern[itemIdx].value); msgPos == -1 {
-			return msgPos, captures{}
+			return msgPos, captures{failPos: pos, expected: pattern[itemIdx].value}
 		}
 		//fmt.Fprintf(os.Stderr, "skipConstant: msg=%s pos=%d(%s)\n", msg, msgPos, msg[:msgPos])
 		itemIdx++
@@ -336,7 +390,7 @@ func matchPattern(msg []byte, pos int, pattern pattern) (nextPos int, captured c
 		}
 		start, end := findConstant(msg, msgPos, pattern[nextCt].value)
 		if start == -1 {
-			return -1, captures{}
+			return -1, captures{failPos: msgPos, expected: pattern[nextCt].value, searched: true}
 		}
 		//fmt.Fprintf(os.Stderr, "findConstant(<<%s>>): <<%s>> in <<%s>>\n", pattern[nextCt].value, msg[start:end], msg)
 		if len(pattern[itemIdx].value) > 0 {
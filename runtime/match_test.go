@@ -5,6 +5,7 @@
 package runtime
 
 import (
+	"regexp"
 	"testing"
 
 	"github.com/adriansr/nwdevice2filebeat/parser"
@@ -163,6 +164,7 @@ func Test_capture(t *testing.T) {
 	for _, test := range []struct {
 		title    string
 		pattern  parser.Pattern
+		varTypes []parser.VarType
 		message  string
 		expected Context
 		err      bool
@@ -221,18 +223,65 @@ func Test_capture(t *testing.T) {
 				},
 			},
 		},
+		{
+			title:   "VARTYPE selects alternative",
+			pattern: P{A{P{F("month"), C(" ")}, P{F("other"), C(" ")}}, F("rest")},
+			varTypes: []parser.VarType{
+				{Name: "month", Regex: "Jan|Feb", IgnoreCase: true},
+			},
+			message: "March 2",
+			expected: Context{
+				Message: s(""),
+				Fields: Fields{
+					"other": "March",
+					"rest":  "2",
+				},
+			},
+		},
+		{
+			title:   "VARTYPE accepts capture",
+			pattern: P{F("month"), C(" "), F("day")},
+			varTypes: []parser.VarType{
+				{Name: "month", Regex: "Jan|Feb", IgnoreCase: true},
+				{Name: "day", Regex: "3[01]|[12][0-9]|0?[1-9]"},
+			},
+			message: "feb 09",
+			expected: Context{
+				Message: s(""),
+				Fields: Fields{
+					"month": "feb",
+					"day":   "09",
+				},
+			},
+		},
+		{
+			title:   "VARTYPE rejects capture",
+			pattern: P{F("month"), C(" "), F("day")},
+			varTypes: []parser.VarType{
+				{Name: "day", Regex: "3[01]|[12][0-9]|0?[1-9]"},
+			},
+			message: "Feb 32",
+			err:     true,
+		},
 	} {
 		t.Run(test.title, func(t *testing.T) {
 			pattern, err := newPattern(test.pattern)
 			if err != nil {
 				t.Fatal(err)
 			}
+			proc := Processor{
+				varTypes: make(map[string]*regexp.Regexp, len(test.varTypes)),
+			}
+			for _, vt := range test.varTypes {
+				proc.varTypes[vt.Name] = regexp.MustCompile(vt.Expr())
+			}
 			m := match{
-				pattern: pattern,
+				pattern:  pattern,
+				varTypes: proc.varTypesForPattern(pattern),
 			}
 			ctx := Context{
 				Message: []byte(test.message),
-				Fields:  make(map[string]string),
+				Fields:  make(Fields),
 			}
 			err = m.Run(&ctx)
 			if !test.err {
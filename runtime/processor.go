@@ -5,6 +5,7 @@
 package runtime
 
 import (
+	"regexp"
 	"strings"
 
 	"github.com/adriansr/nwdevice2filebeat/config"
@@ -32,14 +33,20 @@ type Processor struct {
 	Root      Node
 	logger    util.VerbosityLogger
 	valueMaps map[string]*valueMap
+	regexs    map[string]*regex
+	varTypes  map[string]*regexp.Regexp
 	cfg       *config.Config
 }
 
-type Fields map[string]string
+// Fields holds the values of the fields in an event. Values captured from the
+// log are strings, while functions and the typing stage can store other types
+// (time.Time, int64, float64).
+type Fields map[string]interface{}
 
+// Get returns the value of a field as a string.
 func (f Fields) Get(name string) (string, error) {
 	if value, found := f[name]; found {
-		return value, nil
+		return valueString(value), nil
 	}
 	return "", ErrFieldNotFound
 }
@@ -48,12 +55,19 @@ func (f Fields) Put(name, value string) {
 	f[name] = value
 }
 
+// Set stores a value of any type in a field.
+func (f Fields) Set(name string, value interface{}) {
+	f[name] = value
+}
+
 func New(parser *parser.Parser, warnings *util.Warnings, logger util.Logger) (p *Processor, err error) {
 	if logger == nil {
 		logger = util.DontLog{}
 	}
 	p = &Processor{
 		valueMaps: make(map[string]*valueMap, len(parser.ValueMapsByName)),
+		regexs:    make(map[string]*regex, len(parser.RegexsByName)),
+		varTypes:  make(map[string]*regexp.Regexp, len(parser.VarTypesByName)),
 		logger: util.VerbosityLogger{
 			Logger:   logger,
 			MaxLevel: parser.Config.Verbosity,
@@ -65,28 +79,79 @@ func New(parser *parser.Parser, warnings *util.Warnings, logger util.Logger) (p
 			return nil, err
 		}
 	}
+	for name, rx := range parser.RegexsByName {
+		if p.regexs[name], err = newRegex(rx); err != nil {
+			return nil, err
+		}
+	}
+	for name, vt := range parser.VarTypesByName {
+		if p.varTypes[name], err = regexp.Compile(vt.Expr()); err != nil {
+			return nil, errors.Wrapf(err, "failed compiling VARTYPE %s", name)
+		}
+	}
 	p.Root, err = p.translate(parser.Root)
 	return p, err
 }
 
+// Event is the result of processing a log message.
+type Event struct {
+	Fields Fields
+	Errors multierror.Errors
+	// HeaderID and MessageID are the IDs of the HEADER and MESSAGE that
+	// matched, if any.
+	HeaderID  string
+	MessageID string
+}
+
 func (p *Processor) Process(msg []byte) (fields Fields, errs multierror.Errors) {
+	evt := p.ProcessEvent(msg)
+	return evt.Fields, evt.Errors
+}
+
+// ProcessEvent processes a log message and returns the resulting event.
+func (p *Processor) ProcessEvent(msg []byte) Event {
+	return p.process(msg, nil)
+}
+
+// Explain processes a log message and also returns a trace of how it was
+// processed.
+func (p *Processor) Explain(msg []byte) (Event, *Trace) {
+	trace := &Trace{
+		Message:  string(msg),
+		Headers:  []MatchTrace{},
+		Messages: []MatchTrace{},
+	}
+	return p.process(msg, trace), trace
+}
+
+func (p *Processor) process(msg []byte, trace *Trace) Event {
 	ctx := Context{
 		Message:  msg,
 		Fields:   make(Fields),
 		Warnings: util.NewWarnings(20),
 		Logger:   p.logger,
 		Config:   p.cfg,
+		Trace:    trace,
 	}
 	if err := p.Root.Run(&ctx); err != nil {
-		return nil, append(errs, err)
+		return Event{
+			Errors:   multierror.Errors{err},
+			HeaderID: ctx.HeaderID,
+		}
 	}
 	p.convert(&ctx)
-	return ctx.Fields, ctx.Errors
+	return Event{
+		Fields:    ctx.Fields,
+		Errors:    ctx.Errors,
+		HeaderID:  ctx.HeaderID,
+		MessageID: ctx.MessageID,
+	}
 }
 
 func (p *Processor) convert(ctx *Context) {
-	if dur, found := ctx.Fields["duration"]; found && strings.IndexByte(dur, ':') != -1 {
+	if dur, err := ctx.Fields.Get("duration"); err == nil && strings.IndexByte(dur, ':') != -1 {
 		// Duration is not numeric, try to convert it in HH:mm:ss format.
 		convertDuration.Run(ctx)
 	}
+	p.typeFields(ctx)
 }
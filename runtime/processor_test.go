@@ -0,0 +1,62 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adriansr/nwdevice2filebeat/config"
+	"github.com/adriansr/nwdevice2filebeat/model"
+	"github.com/adriansr/nwdevice2filebeat/parser"
+	"github.com/adriansr/nwdevice2filebeat/util"
+)
+
+func TestProcessor_ProcessEvent(t *testing.T) {
+	dev := model.Device{
+		Headers: []*model.Header{
+			{ID1: "0001", ID2: "0001", Content: "<messageid>: <!payload>"},
+		},
+		TagValMaps: []*model.TagValMap{
+			{PairDelimiter: " ", ValueDelimiter: "="},
+		},
+		Messages: []*model.Message{
+			{ID1: "login", ID2: "login", Content: "user <username> logged in"},
+			{ID1: "logout:1", ID2: "logout", Content: "user <username> logged out"},
+			{ID1: "logout:2", ID2: "logout", Content: "session <session> closed"},
+			{ID1: "kv", ID2: "kv", TagVal: "true", Content: "user=<username>"},
+		},
+	}
+	warnings := util.NewWarnings(10)
+	p, err := parser.New(dev, config.Config{}, &warnings)
+	if !assert.NoError(t, err) {
+		return
+	}
+	proc, err := New(&p, &warnings, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, test := range []struct {
+		log       string
+		headerID  string
+		messageID string
+		errors    int
+	}{
+		{log: "login: user root logged in", headerID: "HEADER#0:0001", messageID: "MESSAGE#0:login"},
+		{log: "logout: session 12 closed", headerID: "HEADER#0:0001", messageID: "MESSAGE#2:logout:2"},
+		{log: "kv: user=root", headerID: "HEADER#0:0001", messageID: "MESSAGE#3:kv"},
+		{log: "logout: bye", headerID: "HEADER#0:0001", errors: 1},
+		{log: "unknown: bye", headerID: "HEADER#0:0001", errors: 1},
+		{log: "no header", errors: 2},
+	} {
+		t.Run(test.log, func(t *testing.T) {
+			evt := proc.ProcessEvent([]byte(test.log))
+			assert.Equal(t, test.headerID, evt.HeaderID)
+			assert.Equal(t, test.messageID, evt.MessageID)
+			assert.Len(t, evt.Errors, test.errors)
+		})
+	}
+}
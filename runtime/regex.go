@@ -0,0 +1,77 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"regexp"
+
+	"github.com/pkg/errors"
+
+	"github.com/adriansr/nwdevice2filebeat/parser"
+)
+
+type regex struct {
+	name        string
+	numParams   int
+	input       int
+	expr        *regexp.Regexp
+	replacement string
+	def         *valueMapEntry
+}
+
+func newRegex(rx *parser.Regex) (out *regex, err error) {
+	out = &regex{
+		name:        rx.Name,
+		numParams:   len(rx.Params),
+		input:       rx.Input,
+		replacement: rx.Replacement,
+	}
+	if out.expr, err = regexp.Compile(rx.Expr); err != nil {
+		return nil, errors.Wrapf(err, "failed compiling REGX %s", rx.Name)
+	}
+	if rx.Default != nil {
+		def, err := newValue(*rx.Default)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed translating REGX %s default", rx.Name)
+		}
+		out.def = &def
+	}
+	return out, nil
+}
+
+type regexCall struct {
+	regex  *regex
+	args   []valueMapEntry
+	target string
+}
+
+func newRegexCall(rx *regex, call parser.RegexCall) (out regexCall, err error) {
+	if len(call.Args) != rx.numParams {
+		return out, errors.Errorf("REGX %s expects %d arguments, got %d", rx.name, rx.numParams, len(call.Args))
+	}
+	out = regexCall{
+		regex:  rx,
+		args:   make([]valueMapEntry, len(call.Args)),
+		target: call.Target,
+	}
+	for idx, arg := range call.Args {
+		if out.args[idx], err = newValue(arg); err != nil {
+			return out, errors.Wrapf(err, "bad argument in call to REGX %s", rx.name)
+		}
+	}
+	return out, nil
+}
+
+func (call regexCall) Run(ctx *Context) error {
+	value := call.args[call.regex.input].Get(ctx)
+	if !call.regex.expr.MatchString(value) {
+		if call.regex.def != nil {
+			ctx.Fields.Put(call.target, (*call.regex.def).Get(ctx))
+		}
+		return nil
+	}
+	ctx.Fields.Put(call.target, call.regex.expr.ReplaceAllLiteralString(value, call.regex.replacement))
+	return nil
+}
@@ -0,0 +1,90 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adriansr/nwdevice2filebeat/parser"
+)
+
+func Test_regexCall_Run(t *testing.T) {
+	defValue := func(v parser.Value) *parser.Value {
+		return &v
+	}
+	for _, test := range []struct {
+		title    string
+		regex    parser.Regex
+		args     []parser.Operation
+		fields   Fields
+		expected Fields
+	}{
+		{
+			title: "replace all",
+			regex: parser.Regex{
+				Params:      []string{"p"},
+				Expr:        `\s+`,
+				Replacement: " ",
+			},
+			args:     []parser.Operation{parser.Field{Name: "a"}},
+			fields:   Fields{"a": "hello    big  world"},
+			expected: Fields{"a": "hello    big  world", "out": "hello big world"},
+		},
+		{
+			title: "replacement is literal",
+			regex: parser.Regex{
+				Params:      []string{"p"},
+				Expr:        `(Stored|Retrieved)`,
+				Replacement: "$1",
+			},
+			args:     []parser.Operation{parser.Constant("Stored")},
+			fields:   Fields{},
+			expected: Fields{"out": "$1"},
+		},
+		{
+			title: "no match without default",
+			regex: parser.Regex{
+				Params:      []string{"p"},
+				Expr:        `-`,
+				Replacement: " ",
+			},
+			args:     []parser.Operation{parser.Field{Name: "a"}},
+			fields:   Fields{"a": "user"},
+			expected: Fields{"a": "user"},
+		},
+		{
+			title: "no match with default",
+			regex: parser.Regex{
+				Params:      []string{"x", "y"},
+				Input:       1,
+				Expr:        `-`,
+				Replacement: " ",
+				Default:     defValue(parser.Field{Name: "a"}),
+			},
+			args:     []parser.Operation{parser.Constant("-"), parser.Constant("user")},
+			fields:   Fields{"a": "default"},
+			expected: Fields{"a": "default", "out": "default"},
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			rx, err := newRegex(&test.regex)
+			if !assert.NoError(t, err) {
+				return
+			}
+			call, err := newRegexCall(rx, parser.RegexCall{
+				Target: "out",
+				Args:   test.args,
+			})
+			if !assert.NoError(t, err) {
+				return
+			}
+			ctx := Context{Fields: test.fields}
+			assert.NoError(t, call.Run(&ctx))
+			assert.Equal(t, test.expected, ctx.Fields)
+		})
+	}
+}
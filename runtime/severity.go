@@ -0,0 +1,34 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+// levelNames are the names for the MESSAGE level attribute, which follows
+// the syslog severity scale.
+var levelNames = []string{
+	"emergency",
+	"alert",
+	"critical",
+	"error",
+	"warning",
+	"notice",
+	"informational",
+	"debug",
+}
+
+// mapSeverity is map_log_level in the javascript library. It sets log.level
+// from event.severity, which is mapped from the level set by the matched
+// MESSAGE, unless it was already populated from a captured severity.
+func mapSeverity(doc Document) {
+	if _, found := doc.Get("log.level"); found {
+		return
+	}
+	level, found := doc.Get("event.severity")
+	if !found {
+		return
+	}
+	if num, ok := level.(int64); ok && num >= 0 && num < int64(len(levelNames)) {
+		doc.Put("log.level", levelNames[num])
+	}
+}
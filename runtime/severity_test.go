@@ -0,0 +1,54 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_mapSeverity(t *testing.T) {
+	for _, test := range []struct {
+		title    string
+		doc      Document
+		expected Document
+	}{
+		{
+			title:    "no severity",
+			doc:      Document{"message": "x"},
+			expected: Document{"message": "x"},
+		},
+		{
+			title: "severity",
+			doc:   Document{"event": Document{"severity": int64(3)}},
+			expected: Document{
+				"event": Document{"severity": int64(3)},
+				"log":   Document{"level": "error"},
+			},
+		},
+		{
+			title: "captured severity",
+			doc: Document{
+				"event": Document{"severity": int64(6)},
+				"log":   Document{"level": "HIGH"},
+			},
+			expected: Document{
+				"event": Document{"severity": int64(6)},
+				"log":   Document{"level": "HIGH"},
+			},
+		},
+		{
+			title:    "out of range",
+			doc:      Document{"event": Document{"severity": int64(9)}},
+			expected: Document{"event": Document{"severity": int64(9)}},
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			mapSeverity(test.doc)
+			assert.Equal(t, test.expected, test.doc)
+		})
+	}
+}
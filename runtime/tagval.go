@@ -0,0 +1,138 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/adriansr/nwdevice2filebeat/parser"
+	"github.com/adriansr/nwdevice2filebeat/util"
+	"github.com/pkg/errors"
+)
+
+// tagValMatch parses key-value messages (MESSAGE with tagval="true"). It
+// follows the same algorithm as tagval() in the javascript library.
+type tagValMatch struct {
+	id string
+	// keys maps message keys to the fields they're captured into.
+	keys map[string]string
+	// varTypes are the VARTYPE expressions that captured fields must match.
+	varTypes  map[string]*regexp.Regexp
+	cfg       parser.TagValMapSettings
+	onSuccess []Node
+	actions   []parser.Operation
+}
+
+func newTagValMatch(tv parser.TagValues) (*tagValMatch, error) {
+	if len(tv.Config.KeyValueSeparator) != 1 {
+		return nil, errors.Errorf("invalid TAGVALMAP ValueDelimiter '%s' (must have 1 character)", tv.Config.KeyValueSeparator)
+	}
+	if tv.Config.PairSeparator == "" {
+		return nil, errors.New("TAGVALMAP has no pair delimiter")
+	}
+	return &tagValMatch{
+		keys: tv.Map,
+		cfg:  tv.Config,
+	}, nil
+}
+
+func (m *tagValMatch) String() string {
+	return "tagval"
+}
+
+func (m *tagValMatch) Run(ctx *Context) error {
+	ctx.Logger.Log(util.LogTrace, "-> run tagval\n")
+	ctx.Logger.Log(util.LogTrace, " > msg ='%s'\n", ctx.Message)
+	ctx.Trace.beginMatch(m.id, m, ctx.Message)
+	captured := make(map[string]string)
+	var prev string
+	for _, pair := range m.split(string(ctx.Message)) {
+		key, value, ok := m.cut(pair)
+		if !ok {
+			prev += pair + m.cfg.PairSeparator
+			continue
+		}
+		key = m.cfg.Unescape(prev + key)
+		prev = ""
+		field, found := m.keys[key]
+		if !found {
+			if field, found = m.keys[strings.TrimSpace(key)]; !found {
+				continue
+			}
+		}
+		value = strings.TrimSpace(value)
+		if open, cls := m.cfg.OpenQuote, m.cfg.CloseQuote; open != "" && cls != "" &&
+			len(value) >= len(open)+len(cls) &&
+			strings.HasPrefix(value, open) && strings.HasSuffix(value, cls) {
+			value = value[len(open) : len(value)-len(cls)]
+		}
+		captured[field] = m.cfg.Unescape(value)
+	}
+	if len(captured) == 0 {
+		ctx.Logger.Log(util.LogTrace, "<- not matched\n")
+		ctx.Trace.matchFailed(0, nil, false, "no known keys found")
+		return ErrNoMatch
+	}
+	if !m.validate(captured) {
+		ctx.Logger.Log(util.LogTrace, "<- rejected by VARTYPE\n")
+		ctx.Trace.matchFailed(0, nil, false, "rejected by VARTYPE")
+		return ErrNoMatch
+	}
+	ctx.Trace.matched()
+	ctx.lastMatch = m.id
+	for key, value := range captured {
+		ctx.Logger.Log(util.LogTrace, " + captured '%s'='%s'\n", key, value)
+		ctx.Fields.Put(key, value)
+	}
+	for idx, act := range m.onSuccess {
+		err := act.Run(ctx)
+		if err != nil {
+			ctx.Errors = append(ctx.Errors, err)
+		}
+		if idx < len(m.actions) {
+			ctx.Trace.action(m.actions[idx], err)
+		}
+	}
+	ctx.Message = ctx.Message[len(ctx.Message):]
+	return nil
+}
+
+// validate checks that the captured values for fields that have a VARTYPE
+// match its expression.
+func (m *tagValMatch) validate(captured map[string]string) bool {
+	for field, value := range captured {
+		if re, found := m.varTypes[field]; found && !re.MatchString(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// split returns the pairs in the message, delimited by unescaped pair
+// separators.
+func (m *tagValMatch) split(msg string) (pairs []string) {
+	sep := m.cfg.PairSeparator
+	for {
+		pos := m.cfg.IndexUnescaped(msg, sep)
+		if pos == -1 {
+			return append(pairs, msg)
+		}
+		pairs = append(pairs, msg[:pos])
+		msg = msg[pos+len(sep):]
+	}
+}
+
+// cut splits a pair at the first unescaped key-value separator. Pairs with
+// an empty key or value are not valid, as in the javascript implementation.
+func (m *tagValMatch) cut(pair string) (key, value string, ok bool) {
+	pos := m.cfg.IndexUnescaped(pair, m.cfg.KeyValueSeparator)
+	if pos <= 0 {
+		return "", "", false
+	}
+	key = pair[:pos]
+	value = strings.TrimLeft(pair[pos+len(m.cfg.KeyValueSeparator):], " ")
+	return key, value, value != ""
+}
@@ -0,0 +1,187 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/adriansr/nwdevice2filebeat/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+// Same cases as test_tvm in liblogparser.js.
+func Test_tagValMatch(t *testing.T) {
+	rsaecat := parser.TagValMapSettings{
+		PairSeparator:     " ",
+		KeyValueSeparator: "=",
+		KeyValueEscape:    "\\",
+	}
+	for _, test := range []struct {
+		title     string
+		config    parser.TagValMapSettings
+		keys      map[string]string
+		varTypes  []parser.VarType
+		onSuccess []Node
+		message   string
+		expected  Fields
+		err       error
+	}{
+		{
+			title: "quoted values",
+			config: parser.TagValMapSettings{
+				PairSeparator:     ",",
+				KeyValueSeparator: "=",
+				OpenQuote:         "[",
+				CloseQuote:        "]",
+			},
+			keys: map[string]string{
+				"key a":     "url",
+				"key_b":     "b",
+				"Operation": "operation",
+			},
+			onSuccess: []Node{&CopyField{Dst: "d", Src: "b"}},
+			message:   "key_b=value for=B, key a = [http://example.com/] ,Operation=[COPY],other stuff=null,,ignore",
+			expected: Fields{
+				"url":       "http://example.com/",
+				"b":         "value for=B",
+				"operation": "COPY",
+				"d":         "value for=B",
+			},
+		},
+		{
+			title: "no match",
+			config: parser.TagValMapSettings{
+				PairSeparator:     ",",
+				KeyValueSeparator: "=",
+			},
+			keys:      map[string]string{"key_b": "b"},
+			onSuccess: []Node{&CopyField{Dst: "d", Src: "b"}},
+			message:   "nothing to see here",
+			expected:  Fields{},
+			err:       ErrNoMatch,
+		},
+		{
+			title: "keys with spaces",
+			config: parser.TagValMapSettings{
+				PairSeparator:     " ",
+				KeyValueSeparator: ":",
+				OpenQuote:         `"`,
+				CloseQuote:        `"`,
+			},
+			keys: map[string]string{
+				"ICMP Type": "icmp_type",
+				"ICMP Code": "icmp_code",
+				"Operation": "operation",
+			},
+			onSuccess: []Node{&SetConstant{Field: "success", Value: "true"}},
+			message:   "Operation:drop ICMP Type:5 ICMP Code:1 ",
+			expected: Fields{
+				"icmp_code": "1",
+				"icmp_type": "5",
+				"operation": "drop",
+				"success":   "true",
+			},
+		},
+		{
+			title:  "escaped separators",
+			config: rsaecat,
+			keys: map[string]string{
+				"agentid":        "agent",
+				"fname":          "filename",
+				"instantIOCName": "threat_name",
+				"sourceModule":   "cs_sourcemodule",
+				"machineScore":   "risk_num",
+			},
+			message: `agentid=4a1f fname=C:\\Windows\\System32\\cmd.exe instantIOCName=Run\ key\=HKLM sourceModule=C:\\Program\ Files\\a.exe machineScore=\=10`,
+			expected: Fields{
+				"agent":           "4a1f",
+				"filename":        `C:\Windows\System32\cmd.exe`,
+				"threat_name":     "Run key=HKLM",
+				"cs_sourcemodule": `C:\Program Files\a.exe`,
+				"risk_num":        "=10",
+			},
+		},
+		{
+			title:  "unescaped paths",
+			config: rsaecat,
+			keys: map[string]string{
+				"fname": "filename",
+				"fsize": "filename_size",
+			},
+			message: `fname=C:\Windows\cmd.exe fsize=1024`,
+			expected: Fields{
+				"filename":      `C:\Windows\cmd.exe`,
+				"filename_size": "1024",
+			},
+		},
+		{
+			title:  "VARTYPE accepts values",
+			config: rsaecat,
+			keys: map[string]string{
+				"fname": "filename",
+				"fsize": "filename_size",
+			},
+			varTypes: []parser.VarType{
+				{Name: "filename_size", Regex: "[0-9]+"},
+			},
+			onSuccess: []Node{&SetConstant{Field: "success", Value: "true"}},
+			message:   `fname=cmd.exe fsize=1024`,
+			expected: Fields{
+				"filename":      "cmd.exe",
+				"filename_size": "1024",
+				"success":       "true",
+			},
+		},
+		{
+			title:  "VARTYPE rejects values",
+			config: rsaecat,
+			keys: map[string]string{
+				"fname": "filename",
+				"fsize": "filename_size",
+			},
+			varTypes: []parser.VarType{
+				{Name: "filename_size", Regex: "[0-9]+"},
+			},
+			onSuccess: []Node{&SetConstant{Field: "success", Value: "true"}},
+			message:   `fname=cmd.exe fsize=1k`,
+			expected:  Fields{},
+			err:       ErrNoMatch,
+		},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			m, err := newTagValMatch(parser.TagValues{Map: test.keys, Config: test.config})
+			if !assert.NoError(t, err) {
+				return
+			}
+			m.onSuccess = test.onSuccess
+			proc := Processor{
+				varTypes: make(map[string]*regexp.Regexp, len(test.varTypes)),
+			}
+			for _, vt := range test.varTypes {
+				proc.varTypes[vt.Name] = regexp.MustCompile(vt.Expr())
+			}
+			m.varTypes = proc.varTypesForTagValues(parser.TagValues{Map: test.keys})
+			ctx := Context{
+				Message: []byte(test.message),
+				Fields:  make(Fields),
+			}
+			err = m.Run(&ctx)
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.expected, ctx.Fields)
+			if err == nil {
+				assert.Empty(t, ctx.Message)
+			}
+		})
+	}
+}
+
+func Test_newTagValMatch(t *testing.T) {
+	_, err := newTagValMatch(parser.TagValues{
+		Map:    map[string]string{"a": "b"},
+		Config: parser.TagValMapSettings{PairSeparator: " ", KeyValueSeparator: "=>"},
+	})
+	assert.Error(t, err)
+}
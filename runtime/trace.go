@@ -0,0 +1,211 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/adriansr/nwdevice2filebeat/parser"
+)
+
+// How the MESSAGEs to try were selected from the messageid.
+const (
+	SelectMapped      = "mapped"
+	SelectCatchAll    = "catch-all"
+	SelectNotMapped   = "not mapped"
+	SelectNoMessageID = "no messageid"
+)
+
+// Trace records the steps taken to process a log message: the HEADERs and
+// MESSAGEs that were tried, where they failed to match, and the actions that
+// ran. It's only collected when Context.Trace is set, and all its methods can
+// be called on a nil Trace.
+type Trace struct {
+	Message string `json:"message"`
+	// Headers are the HEADERs tried, in order.
+	Headers []MatchTrace `json:"headers"`
+	// MessageID is the messageid used to select MESSAGEs, and Selection how
+	// they were selected.
+	MessageID string `json:"messageid,omitempty"`
+	Selection string `json:"selection,omitempty"`
+	// Messages are the MESSAGEs tried, in order.
+	Messages []MatchTrace `json:"messages"`
+
+	inMessages bool
+}
+
+// MatchTrace is an attempt to match a HEADER or MESSAGE.
+type MatchTrace struct {
+	ID      string `json:"id"`
+	Pattern string `json:"pattern"`
+	// Input is the part of the log the pattern was applied to.
+	Input   string `json:"input"`
+	Matched bool   `json:"matched"`
+	// Offset is the position in Input where the match failed, and Expected
+	// the constant the pattern needed there (or after it, when Searched is
+	// set). Reason describes the failure.
+	Offset   int           `json:"offset"`
+	Expected string        `json:"expected,omitempty"`
+	Searched bool          `json:"searched,omitempty"`
+	Reason   string        `json:"reason,omitempty"`
+	Actions  []ActionTrace `json:"actions,omitempty"`
+}
+
+// ActionTrace is an OnSuccess action run after a match.
+type ActionTrace struct {
+	Action string `json:"action"`
+	Error  string `json:"error,omitempty"`
+}
+
+func (t *Trace) current() *MatchTrace {
+	list := t.Headers
+	if t.inMessages {
+		list = t.Messages
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	return &list[len(list)-1]
+}
+
+func (t *Trace) beginMatch(id string, pattern fmt.Stringer, input []byte) {
+	if t == nil {
+		return
+	}
+	m := MatchTrace{
+		ID:      id,
+		Pattern: pattern.String(),
+		Input:   string(input),
+	}
+	if t.inMessages {
+		t.Messages = append(t.Messages, m)
+	} else {
+		t.Headers = append(t.Headers, m)
+	}
+}
+
+func (t *Trace) matchFailed(offset int, expected []byte, searched bool, reason string) {
+	if t == nil {
+		return
+	}
+	if m := t.current(); m != nil {
+		m.Offset, m.Expected, m.Searched, m.Reason = offset, string(expected), searched, reason
+	}
+}
+
+func (t *Trace) matched() {
+	if t == nil {
+		return
+	}
+	if m := t.current(); m != nil {
+		m.Matched = true
+	}
+}
+
+func (t *Trace) action(op parser.Operation, err error) {
+	if t == nil || op == nil {
+		return
+	}
+	if m := t.current(); m != nil {
+		act := ActionTrace{Action: op.Hashable()}
+		if err != nil {
+			act.Error = err.Error()
+		}
+		m.Actions = append(m.Actions, act)
+	}
+}
+
+func (t *Trace) selectMessages(messageID, selection string) {
+	if t == nil {
+		return
+	}
+	t.inMessages = true
+	t.MessageID, t.Selection = messageID, selection
+}
+
+// Length of the input shown after the offset where a match failed.
+const traceContext = 32
+
+// WriteText writes the trace as a report, followed by the resulting event.
+func (t *Trace) WriteText(w io.Writer, evt Event) {
+	fmt.Fprintf(w, "Log: %q\n", t.Message)
+	fmt.Fprintf(w, "\nHEADERs tried: %d\n", len(t.Headers))
+	for _, m := range t.Headers {
+		m.writeText(w)
+	}
+	switch t.Selection {
+	case "":
+		fmt.Fprintf(w, "\nNo MESSAGEs selected.\n")
+	case SelectNoMessageID:
+		fmt.Fprintf(w, "\nNo MESSAGEs selected: %s.\n", t.Selection)
+	default:
+		fmt.Fprintf(w, "\nmessageid: %q (%s)\n", t.MessageID, t.Selection)
+	}
+	if len(t.Messages) > 0 {
+		fmt.Fprintf(w, "\nMESSAGEs tried: %d\n", len(t.Messages))
+		for _, m := range t.Messages {
+			m.writeText(w)
+		}
+	}
+	fmt.Fprintf(w, "\nResult:\n")
+	fmt.Fprintf(w, "    header: %s\n", orNone(evt.HeaderID))
+	fmt.Fprintf(w, "    message: %s\n", orNone(evt.MessageID))
+	names := make([]string, 0, len(evt.Fields))
+	for name := range evt.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(w, "    fields: %d\n", len(names))
+	for _, name := range names {
+		fmt.Fprintf(w, "        %s: %q\n", name, valueString(evt.Fields[name]))
+	}
+	fmt.Fprintf(w, "    errors: %d\n", len(evt.Errors))
+	for _, err := range evt.Errors {
+		fmt.Fprintf(w, "        %v\n", err)
+	}
+}
+
+func (m MatchTrace) writeText(w io.Writer) {
+	if !m.Matched {
+		fmt.Fprintf(w, "  %s: no match\n", m.ID)
+	} else {
+		fmt.Fprintf(w, "  %s: matched\n", m.ID)
+	}
+	fmt.Fprintf(w, "    pattern: %s\n", m.Pattern)
+	fmt.Fprintf(w, "    input: %q\n", m.Input)
+	if !m.Matched {
+		found := m.Input
+		if m.Offset <= len(found) {
+			found = found[m.Offset:]
+		}
+		if len(found) > traceContext {
+			found = found[:traceContext] + "..."
+		}
+		switch {
+		case m.Expected == "":
+			fmt.Fprintf(w, "    failed at offset %d: %s\n", m.Offset, m.Reason)
+		case m.Searched:
+			fmt.Fprintf(w, "    failed at offset %d: %q not found in %q\n", m.Offset, m.Expected, found)
+		default:
+			fmt.Fprintf(w, "    failed at offset %d: expected %q, found %q\n", m.Offset, m.Expected, found)
+		}
+	}
+	for _, act := range m.Actions {
+		if act.Error != "" {
+			fmt.Fprintf(w, "    action %s: %s\n", act.Action, act.Error)
+		} else {
+			fmt.Fprintf(w, "    action %s: ok\n", act.Action)
+		}
+	}
+}
+
+func orNone(id string) string {
+	if id == "" {
+		return "(none)"
+	}
+	return id
+}
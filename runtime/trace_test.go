@@ -0,0 +1,96 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adriansr/nwdevice2filebeat/config"
+	"github.com/adriansr/nwdevice2filebeat/model"
+	"github.com/adriansr/nwdevice2filebeat/parser"
+	"github.com/adriansr/nwdevice2filebeat/util"
+)
+
+func TestProcessor_Explain(t *testing.T) {
+	dev := model.Device{
+		Headers: []*model.Header{
+			{ID1: "0001", ID2: "0001", Content: "%ASA-<level>-<messageid>: <!payload>"},
+			{ID1: "0002", ID2: "0002", Content: "<messageid>: <!payload>"},
+		},
+		Messages: []*model.Message{
+			{ID1: "login:1", ID2: "login", Content: "user <username> logged in"},
+			{ID1: "login:2", ID2: "login", Content: "session <session> opened for <username>"},
+		},
+	}
+	warnings := util.NewWarnings(10)
+	p, err := parser.New(dev, config.Config{}, &warnings)
+	if !assert.NoError(t, err) {
+		return
+	}
+	proc, err := New(&p, &warnings, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	evt, trace := proc.Explain([]byte("login: session 12 opened by root"))
+	assert.Equal(t, "HEADER#1:0002", evt.HeaderID)
+	assert.Empty(t, evt.MessageID)
+	if assert.Len(t, trace.Headers, 2) {
+		h := trace.Headers[0]
+		assert.Equal(t, "HEADER#0:0001", h.ID)
+		assert.False(t, h.Matched)
+		assert.Equal(t, 0, h.Offset)
+		assert.Equal(t, "%ASA-", h.Expected)
+		assert.False(t, h.Searched)
+
+		h = trace.Headers[1]
+		assert.Equal(t, "HEADER#1:0002", h.ID)
+		assert.True(t, h.Matched)
+		if assert.Len(t, h.Actions, 1) {
+			assert.Contains(t, h.Actions[0].Action, "header_id")
+			assert.Empty(t, h.Actions[0].Error)
+		}
+	}
+	assert.Equal(t, "login", trace.MessageID)
+	assert.Equal(t, SelectMapped, trace.Selection)
+	if assert.Len(t, trace.Messages, 2) {
+		m := trace.Messages[0]
+		assert.Equal(t, "MESSAGE#0:login:1", m.ID)
+		assert.Equal(t, "session 12 opened by root", m.Input)
+		assert.Equal(t, 0, m.Offset)
+		assert.Equal(t, "user", m.Expected)
+
+		m = trace.Messages[1]
+		assert.Equal(t, "MESSAGE#1:login:2", m.ID)
+		assert.False(t, m.Matched)
+		assert.Equal(t, 7, m.Offset)
+		assert.Equal(t, "opened for", m.Expected)
+		assert.True(t, m.Searched)
+	}
+
+	var sb strings.Builder
+	trace.WriteText(&sb, evt)
+	report := sb.String()
+	assert.Contains(t, report, `failed at offset 0: expected "%ASA-", found "login: session 12 opened by root"`)
+	assert.Contains(t, report, `messageid: "login" (mapped)`)
+	assert.Contains(t, report, `failed at offset 7: "opened for" not found in " 12 opened by root"`)
+	assert.Contains(t, report, "message: (none)")
+
+	_, trace = proc.Explain([]byte("logout: bye"))
+	assert.Equal(t, "logout", trace.MessageID)
+	assert.Equal(t, SelectNotMapped, trace.Selection)
+	assert.Empty(t, trace.Messages)
+
+	// Tracing doesn't change the result.
+	line := []byte("login: user root logged in")
+	evt, trace = proc.Explain(line)
+	assert.Equal(t, proc.ProcessEvent(line), evt)
+	if assert.Len(t, trace.Messages, 1) {
+		assert.True(t, trace.Messages[0].Matched)
+	}
+}
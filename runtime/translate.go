@@ -5,6 +5,8 @@
 package runtime
 
 import (
+	"regexp"
+
 	"github.com/adriansr/nwdevice2filebeat/parser"
 	"github.com/pkg/errors"
 )
@@ -34,13 +36,32 @@ func (proc *Processor) translate(op parser.Operation) (result Node, err error) {
 		return &sel, nil
 
 	case parser.Match:
+		if v.TagValues.IsSet() {
+			match, err := newTagValMatch(v.TagValues)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error converting tagval message %s", v.ID)
+			}
+			match.id = v.ID
+			match.varTypes = proc.varTypesForTagValues(v.TagValues)
+			match.actions = v.OnSuccess
+			match.onSuccess = make([]Node, len(v.OnSuccess))
+			for idx, op := range v.OnSuccess {
+				if match.onSuccess[idx], err = proc.translate(op); err != nil {
+					return nil, errors.Wrap(err, "error translating tagval's onsuccess")
+				}
+			}
+			return match, nil
+		}
 		pattern, err := newPattern(v.Pattern)
 		if err != nil {
 			return nil, errors.Wrap(err, "error converting pattern")
 		}
 		match := match{
+			id:        v.ID,
 			pattern:   pattern,
 			onSuccess: make([]Node, len(v.OnSuccess)),
+			actions:   v.OnSuccess,
+			varTypes:  proc.varTypesForPattern(pattern),
 		}
 		// TODO logging log.Printf("match %s has %d ops:", v.ID, len(v.OnSuccess))
 		for idx, op := range v.OnSuccess {
@@ -83,9 +104,16 @@ func (proc *Processor) translate(op parser.Operation) (result Node, err error) {
 		return newFunction(v.Function, v.Target, v.Args)
 
 	case parser.MsgIdSelect:
-		node := MapSelect{}
+		node := MapSelect{
+			Map: make(map[string]Node, len(v.Map)),
+		}
 		for k, idx := range v.Map {
-			if node[k], err = proc.translate(v.Nodes[idx]); err != nil {
+			if node.Map[k], err = proc.translate(v.Nodes[idx]); err != nil {
+				return nil, err
+			}
+		}
+		if v.Default != nil {
+			if node.Default, err = proc.translate(v.Default); err != nil {
 				return nil, err
 			}
 		}
@@ -115,6 +143,13 @@ func (proc *Processor) translate(op parser.Operation) (result Node, err error) {
 			target:   v.Target,
 		}, nil
 
+	case parser.RegexCall:
+		rx, ok := proc.regexs[v.Name]
+		if !ok {
+			return nil, errors.Errorf("call to unknown REGX: %s", v.Name)
+		}
+		return newRegexCall(rx, v)
+
 	case parser.URLExtract:
 		return urlExtract(v), nil
 
@@ -122,3 +157,46 @@ func (proc *Processor) translate(op parser.Operation) (result Node, err error) {
 		return nil, errors.Errorf("unknown type to translate: %T", v)
 	}
 }
+
+// varTypesForPattern returns the VARTYPE validations that apply to the
+// fields captured by the given pattern.
+func (proc *Processor) varTypesForPattern(pattern [][]pattern) (result map[string]*regexp.Regexp) {
+	if len(proc.varTypes) == 0 {
+		return nil
+	}
+	for _, chunk := range pattern {
+		for _, alt := range chunk {
+			for _, elem := range alt {
+				if !elem.isCapture {
+					continue
+				}
+				result = proc.addVarType(result, string(elem.value))
+			}
+		}
+	}
+	return result
+}
+
+// varTypesForTagValues returns the VARTYPE validations that apply to the
+// fields captured by a tagval message.
+func (proc *Processor) varTypesForTagValues(tv parser.TagValues) (result map[string]*regexp.Regexp) {
+	if len(proc.varTypes) == 0 {
+		return nil
+	}
+	for _, field := range tv.Map {
+		result = proc.addVarType(result, field)
+	}
+	return result
+}
+
+// addVarType adds the VARTYPE validation for field to result, if there is
+// one, allocating result when necessary.
+func (proc *Processor) addVarType(result map[string]*regexp.Regexp, field string) map[string]*regexp.Regexp {
+	if re, found := proc.varTypes[field]; found {
+		if result == nil {
+			result = make(map[string]*regexp.Regexp)
+		}
+		result[field] = re
+	}
+	return result
+}
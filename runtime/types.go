@@ -0,0 +1,63 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/adriansr/nwdevice2filebeat/ecs"
+	"github.com/adriansr/nwdevice2filebeat/util"
+)
+
+// ConversionError is reported when the value of a field can't be converted to
+// the type in its format.
+type ConversionError struct {
+	Field string
+	Value string
+	Type  ecs.Conversion
+}
+
+func (e ConversionError) Error() string {
+	return fmt.Sprintf("failed to convert field '%s' = '%s' to %s", e.Field, e.Value, e.Type)
+}
+
+// typeFields is the typing stage, which runs at the end of convert. Mapped
+// fields with a format in the mappings table are converted to their type. As
+// in do_populate, a field that fails to convert is logged and kept as a
+// string, so it won't be mapped.
+func (p *Processor) typeFields(ctx *Context) {
+	types := p.cfg.FieldMappings.Types
+	if len(types) == 0 {
+		return
+	}
+	names := make([]string, 0, len(ctx.Fields))
+	for name, value := range ctx.Fields {
+		if str, ok := value.(string); ok && str != "" && types[name] != ecs.ConvertNone {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		str := ctx.Fields[name].(string)
+		typed, ok := convertValue(types[name], str)
+		if !ok {
+			err := ConversionError{Field: name, Value: str, Type: types[name]}
+			ctx.Logger.Log(util.LogDebug, "%v\n", err)
+			continue
+		}
+		ctx.Fields.Set(name, typed)
+	}
+}
+
+// valueString returns the string representation of a field value, used when
+// a field is the input of a function. Timestamps are formatted with
+// time.Time.String, a layout that toDate understands.
+func valueString(value interface{}) string {
+	if str, ok := value.(string); ok {
+		return str
+	}
+	return fmt.Sprint(value)
+}
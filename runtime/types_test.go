@@ -0,0 +1,67 @@
+//  Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+//  or more contributor license agreements. Licensed under the Elastic License;
+//  you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adriansr/nwdevice2filebeat/config"
+	"github.com/adriansr/nwdevice2filebeat/ecs"
+)
+
+func TestProcessor_typeFields(t *testing.T) {
+	date := time.Date(2020, 3, 10, 14, 27, 3, 0, time.UTC)
+	cfg := config.Config{
+		FieldMappings: ecs.Mappings{
+			Types: map[string]ecs.Conversion{
+				"saddr":      ecs.ConvertIP,
+				"sport":      ecs.ConvertLong,
+				"bytes":      ecs.ConvertLong,
+				"duration":   ecs.ConvertDouble,
+				"event_time": ecs.ConvertDate,
+				"smacaddr":   ecs.ConvertMAC,
+			},
+		},
+	}
+	p := Processor{cfg: &cfg}
+	ctx := Context{
+		Fields: Fields{
+			"username":   "root",
+			"saddr":      "[fe80::1%eth0]",
+			"sport":      "22",
+			"bytes":      "many",
+			"duration":   "",
+			"event_time": date,
+			"smacaddr":   "00:50:56:c0:00:08",
+		},
+	}
+	p.typeFields(&ctx)
+	assert.Equal(t, Fields{
+		"username":   "root",
+		"saddr":      "fe80::1",
+		"sport":      int64(22),
+		"bytes":      "many",
+		"duration":   "",
+		"event_time": date,
+		"smacaddr":   "00:50:56:c0:00:08",
+	}, ctx.Fields)
+	assert.Empty(t, ctx.Errors)
+}
+
+func TestConversionError(t *testing.T) {
+	err := ConversionError{Field: "bytes", Value: "many", Type: ecs.ConvertLong}
+	assert.Equal(t, "failed to convert field 'bytes' = 'many' to long", err.Error())
+}
+
+func Test_valueString(t *testing.T) {
+	assert.Equal(t, "text", valueString("text"))
+	assert.Equal(t, "22", valueString(int64(22)))
+	assert.Equal(t, "1.5", valueString(1.5))
+	assert.Equal(t, "2020-03-10 14:27:03 +0000 UTC",
+		valueString(time.Date(2020, 3, 10, 14, 27, 3, 0, time.UTC)))
+}